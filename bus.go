@@ -24,6 +24,17 @@ import (
 type Channel = bus.Channel
 type MessageBus bus.MessageBus
 
+type SubscriptionState = bus.SubscriptionState
+
+const (
+	SubscriptionLost     = bus.SubscriptionLost
+	SubscriptionRestored = bus.SubscriptionRestored
+)
+
+// SubscriptionStateFunc is called when one of a client's or server's subscriptions fails, and again once it has
+// been restored. Subscriptions are retried with backoff until they succeed or are closed.
+type SubscriptionStateFunc = bus.SubscriptionStateFunc
+
 func NewLocalMessageBus() MessageBus {
 	return bus.NewLocalMessageBus()
 }
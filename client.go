@@ -19,12 +19,15 @@ import (
 	"time"
 
 	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/psrpc/pkg/metadata"
 )
 
 const (
 	DefaultClientTimeout        = time.Second * 3
 	DefaultAffinityTimeout      = time.Second
 	DefaultAffinityShortCircuit = time.Millisecond * 200
+	DefaultStreamResumeTTL      = time.Minute
 )
 
 type ClientOption func(*ClientOpts)
@@ -40,6 +43,10 @@ type ClientOpts struct {
 	RpcInterceptors      []ClientRPCInterceptor
 	MultiRPCInterceptors []ClientMultiRPCInterceptor
 	StreamInterceptors   []StreamInterceptor
+	StickyRoutingTTL     time.Duration
+	StreamResumeTTL      time.Duration
+	MetadataPropagation  *metadata.PropagationPolicy
+	SubscriptionState    SubscriptionStateFunc
 }
 
 func WithClientID(id string) ClientOption {
@@ -66,6 +73,30 @@ func WithClientChannelSize(size int) ClientOption {
 	}
 }
 
+// WithClientStickyRouting remembers the server selected for each request routing key. Later requests with the
+// same key skip server selection and are sent directly to that server, until it fails or goes unused for ttl.
+func WithClientStickyRouting(ttl time.Duration) ClientOption {
+	return func(o *ClientOpts) {
+		o.StickyRoutingTTL = ttl
+	}
+}
+
+// WithClientStreamResumeTTL sets how long a lost resumable stream keeps its unacknowledged messages for
+// a stream resumed with its token (default 1 minute)
+func WithClientStreamResumeTTL(ttl time.Duration) ClientOption {
+	return func(o *ClientOpts) {
+		o.StreamResumeTTL = ttl
+	}
+}
+
+// WithClientSubscriptionStateFunc is called when the client's subscriptions, including those created with Join,
+// fail and are restored
+func WithClientSubscriptionStateFunc(fn SubscriptionStateFunc) ClientOption {
+	return func(o *ClientOpts) {
+		o.SubscriptionState = fn
+	}
+}
+
 // Request hooks are called as soon as the request is made
 type ClientRequestHook func(ctx context.Context, req proto.Message, info RPCInfo)
 
@@ -113,6 +144,41 @@ func WithClientStreamInterceptors(interceptors ...StreamInterceptor) ClientOptio
 	}
 }
 
+// WithClientMetadataPropagation forwards incoming metadata matching the policy when requests are made from a
+// handler's context, along with the route of the incoming request. Requests and multi-requests are propagated
+// by interceptors installed in the order the option is given. Streams are opened before their interceptors
+// run, so the client propagates their metadata itself.
+func WithClientMetadataPropagation(policy metadata.PropagationPolicy) ClientOption {
+	return func(o *ClientOpts) {
+		o.MetadataPropagation = &policy
+		o.RpcInterceptors = append(o.RpcInterceptors, newPropagationRPCInterceptor(&policy))
+		o.MultiRPCInterceptors = append(o.MultiRPCInterceptors, newPropagationMultiRPCInterceptor(&policy))
+	}
+}
+
+func newPropagationRPCInterceptor(policy *metadata.PropagationPolicy) ClientRPCInterceptor {
+	return func(info RPCInfo, next ClientRPCHandler) ClientRPCHandler {
+		return func(ctx context.Context, req proto.Message, opts ...RequestOption) (proto.Message, error) {
+			return next(metadata.NewContextWithPropagatedMetadata(ctx, policy), req, opts...)
+		}
+	}
+}
+
+func newPropagationMultiRPCInterceptor(policy *metadata.PropagationPolicy) ClientMultiRPCInterceptor {
+	return func(info RPCInfo, next ClientMultiRPCHandler) ClientMultiRPCHandler {
+		return &propagationMultiRPCHandler{ClientMultiRPCHandler: next, policy: policy}
+	}
+}
+
+type propagationMultiRPCHandler struct {
+	ClientMultiRPCHandler
+	policy *metadata.PropagationPolicy
+}
+
+func (h *propagationMultiRPCHandler) Send(ctx context.Context, msg proto.Message, opts ...RequestOption) error {
+	return h.ClientMultiRPCHandler.Send(metadata.NewContextWithPropagatedMetadata(ctx, h.policy), msg, opts...)
+}
+
 func WithClientOptions(opts ...ClientOption) ClientOption {
 	return func(o *ClientOpts) {
 		for _, opt := range opts {
@@ -0,0 +1,91 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package psrpc
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const DefaultConsumeKeyQueueSize = 100
+
+// Consumer handles the messages of a subscription until it is closed or its context is done
+type Consumer interface {
+	// Close stops receiving messages, and waits for the handlers in progress to return. It must not be called
+	// from a handler, which would wait for itself. Handlers can stop the consumer by cancelling its context.
+	Close() error
+	// Done is closed once the consumer has stopped
+	Done() <-chan struct{}
+}
+
+type SubscriptionInterceptor func(info RPCInfo, next SubscriptionHandler) SubscriptionHandler
+type SubscriptionHandler func(ctx context.Context, msg proto.Message) error
+
+type ConsumeOption func(*ConsumeOpts)
+
+type ConsumeOpts struct {
+	Concurrency   int                            // (default 1) messages handled at once
+	OrderingKey   func(msg proto.Message) string // messages with the same key are handled one at a time, in order
+	KeyQueueSize  int                            // (default 100) messages waiting behind each busy ordering key
+	RecoverPanics bool
+	Interceptors  []SubscriptionInterceptor
+	ErrorHandler  func(msg proto.Message, err error) // (default logs the error) called when the handler fails
+}
+
+// WithConsumeConcurrency handles up to n messages at once. Messages which are received while n are in progress
+// wait for a handler to return. By default, messages are handled one at a time in the order they are received.
+func WithConsumeConcurrency(n int) ConsumeOption {
+	return func(o *ConsumeOpts) {
+		o.Concurrency = n
+	}
+}
+
+// WithConsumeOrderingKey keeps the order of messages with the same key when handling messages concurrently.
+// A consumer reads a single topic, so its messages are already handled serially, in topic order, without
+// WithConsumeConcurrency. The key keeps that order for each entity, such as a room, while other keys run in
+// parallel. Messages waiting behind a busy key don't hold a concurrency slot.
+func WithConsumeOrderingKey(fn func(msg proto.Message) string) ConsumeOption {
+	return func(o *ConsumeOpts) {
+		o.OrderingKey = fn
+	}
+}
+
+// WithConsumeKeyQueueSize limits the messages waiting behind each busy ordering key. Once a key's queue is full,
+// the consumer stops receiving messages until the key's handler catches up.
+func WithConsumeKeyQueueSize(size int) ConsumeOption {
+	return func(o *ConsumeOpts) {
+		o.KeyQueueSize = size
+	}
+}
+
+// WithConsumePanicRecovery reports handler panics to the error handler as Internal errors
+func WithConsumePanicRecovery() ConsumeOption {
+	return func(o *ConsumeOpts) {
+		o.RecoverPanics = true
+	}
+}
+
+func WithConsumeInterceptors(interceptors ...SubscriptionInterceptor) ConsumeOption {
+	return func(o *ConsumeOpts) {
+		o.Interceptors = append(o.Interceptors, interceptors...)
+	}
+}
+
+func WithConsumeErrorHandler(fn func(msg proto.Message, err error)) ConsumeOption {
+	return func(o *ConsumeOpts) {
+		o.ErrorHandler = fn
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/twitchtv/twirp"
 	spb "google.golang.org/genproto/googleapis/rpc/status"
@@ -29,16 +30,35 @@ import (
 )
 
 var (
-	ErrRequestCanceled = NewErrorf(Canceled, "request canceled")
-	ErrRequestTimedOut = NewErrorf(DeadlineExceeded, "request timed out")
-	ErrNoResponse      = NewErrorf(Unavailable, "no response from servers")
-	ErrStreamEOF       = NewError(Unavailable, io.EOF)
-	ErrClientClosed    = NewErrorf(Canceled, "client is closed")
-	ErrServerClosed    = NewErrorf(Canceled, "server is closed")
-	ErrStreamClosed    = NewErrorf(Canceled, "stream closed")
-	ErrSlowConsumer    = NewErrorf(Unavailable, "stream message discarded by slow consumer")
+	ErrRequestCanceled  = NewErrorf(Canceled, "request canceled")
+	ErrRequestTimedOut  = NewErrorf(DeadlineExceeded, "request timed out")
+	ErrNoResponse       = NewErrorf(Unavailable, "no response from servers")
+	ErrStreamEOF        = NewError(Unavailable, io.EOF)
+	ErrClientClosed     = NewErrorf(Canceled, "client is closed")
+	ErrServerClosed     = NewErrorf(Canceled, "server is closed")
+	ErrServerOverloaded = NewErrorf(ResourceExhausted, "server is overloaded")
+	ErrServerNotFound   = NewErrorf(NotFound, "server not found")
+	ErrRequestRejected  = NewErrorf(Unavailable, "request rejected by server")
+	ErrStreamClosed     = NewErrorf(Canceled, "stream closed")
+	ErrSlowConsumer     = NewErrorf(Unavailable, "stream message discarded by slow consumer")
+	ErrPeerUnresponsive = NewErrorf(Unavailable, "stream peer is unresponsive")
+	ErrStreamSendClosed = NewErrorf(FailedPrecondition, "stream send closed")
 )
 
+// MissingResponsesError is the final result of a multi-RPC which timed out or was canceled before all
+// of its expected servers responded
+type MissingResponsesError struct {
+	ServerIDs []string // expected servers which did not respond
+	Count     int      // number of missing responses
+}
+
+func (e *MissingResponsesError) Error() string {
+	if len(e.ServerIDs) == 0 {
+		return fmt.Sprintf("missing %d responses", e.Count)
+	}
+	return fmt.Sprintf("missing %d responses from servers %s", e.Count, strings.Join(e.ServerIDs, ", "))
+}
+
 type Error interface {
 	error
 	Code() ErrorCode
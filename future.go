@@ -0,0 +1,105 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package psrpc
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Future is the result of an asynchronous RPC
+type Future[ResponseType proto.Message] struct {
+	done chan struct{}
+	res  ResponseType
+	err  error
+}
+
+// NewFuture runs fn in a new goroutine, and resolves the future with its result
+func NewFuture[ResponseType proto.Message](fn func() (ResponseType, error)) *Future[ResponseType] {
+	f := &Future[ResponseType]{done: make(chan struct{})}
+	go func() {
+		f.res, f.err = fn()
+		close(f.done)
+	}()
+	return f
+}
+
+// Done is closed once the result is ready
+func (f *Future[ResponseType]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait returns the result once it is ready. Canceling ctx stops waiting, but does not cancel the request.
+func (f *Future[ResponseType]) Wait(ctx context.Context) (ResponseType, error) {
+	select {
+	case <-f.done:
+		return f.res, f.err
+	case <-ctx.Done():
+		var res ResponseType
+		return res, ctx.Err()
+	}
+}
+
+// All waits for every future, and returns the results in the same order. If any request fails, its result is
+// left empty and the results are returned with a *MultiError.
+func All[ResponseType proto.Message](ctx context.Context, futures ...*Future[ResponseType]) ([]ResponseType, error) {
+	results := make([]ResponseType, len(futures))
+	var errs []error
+	for i, f := range futures {
+		res, err := f.Wait(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return results, err
+			}
+			errs = append(errs, err)
+			continue
+		}
+		results[i] = res
+	}
+	return results, newMultiError(nil, errs)
+}
+
+// Any returns the first successful result. If every request fails, the errors are returned in a *MultiError
+// wrapping ErrInsufficientResponses.
+func Any[ResponseType proto.Message](ctx context.Context, futures ...*Future[ResponseType]) (ResponseType, error) {
+	pending := make(chan *Future[ResponseType], len(futures))
+	for _, f := range futures {
+		go func(f *Future[ResponseType]) {
+			select {
+			case <-f.done:
+				pending <- f
+			case <-ctx.Done():
+			}
+		}(f)
+	}
+
+	var errs []error
+	for range futures {
+		select {
+		case f := <-pending:
+			if f.err == nil {
+				return f.res, nil
+			}
+			errs = append(errs, f.err)
+		case <-ctx.Done():
+			var res ResponseType
+			return res, ctx.Err()
+		}
+	}
+
+	var res ResponseType
+	return res, newMultiError(ErrInsufficientResponses, errs)
+}
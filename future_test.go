@@ -0,0 +1,79 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package psrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestFuture(t *testing.T) {
+	resolve := func(v string, err error, delay time.Duration) *Future[*wrapperspb.StringValue] {
+		return NewFuture(func() (*wrapperspb.StringValue, error) {
+			time.Sleep(delay)
+			if err != nil {
+				return nil, err
+			}
+			return wrapperspb.String(v), nil
+		})
+	}
+	errTest := errors.New("test error")
+
+	t.Run("Wait", func(t *testing.T) {
+		f := resolve("a", nil, 10*time.Millisecond)
+		res, err := f.Wait(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "a", res.Value)
+
+		select {
+		case <-f.Done():
+		default:
+			t.Fatal("future is not done")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = resolve("b", nil, time.Second).Wait(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("All", func(t *testing.T) {
+		res, err := All(context.Background(), resolve("a", nil, 20*time.Millisecond), resolve("b", nil, 0))
+		require.NoError(t, err)
+		require.Equal(t, "a", res[0].Value)
+		require.Equal(t, "b", res[1].Value)
+
+		res, err = All(context.Background(), resolve("a", nil, 0), resolve("", errTest, 0))
+		var e *MultiError
+		require.ErrorAs(t, err, &e)
+		require.Equal(t, []error{errTest}, e.Errors)
+		require.Equal(t, "a", res[0].Value)
+		require.Nil(t, res[1])
+	})
+
+	t.Run("Any", func(t *testing.T) {
+		res, err := Any(context.Background(), resolve("a", nil, time.Second), resolve("", errTest, 0), resolve("c", nil, 10*time.Millisecond))
+		require.NoError(t, err)
+		require.Equal(t, "c", res.Value)
+
+		_, err = Any(context.Background(), resolve("", errTest, 0), resolve("", errTest, 0))
+		require.ErrorIs(t, err, ErrInsufficientResponses)
+	})
+}
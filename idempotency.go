@@ -0,0 +1,195 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package psrpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const DefaultIdempotencyTTL = time.Minute
+
+// IdempotencyCache stores the responses to requests with idempotency keys, so that duplicate requests are
+// answered without running the handler again. Only successful responses are stored, so a retry of a failed
+// request runs the handler again.
+type IdempotencyCache interface {
+	// Reserve claims a key for a new execution, returning false if it was already claimed
+	Reserve(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Load waits for the response to a claimed key until ctx is done
+	Load(ctx context.Context, key string) ([]byte, error)
+	// Store saves the response to a claimed key
+	Store(ctx context.Context, key string, res []byte, ttl time.Duration) error
+	// Release forgets a claimed key without a response
+	Release(ctx context.Context, key string) error
+}
+
+type IdempotencyOpts struct {
+	Cache IdempotencyCache
+	TTL   time.Duration // (default 1m) time a key is remembered after it is first seen
+}
+
+var errIdempotencyKeyExpired = errors.New("idempotency key expired")
+
+type memoryIdempotencyCache struct {
+	mu        sync.Mutex
+	entries   map[string]*idempotencyEntry
+	nextSweep time.Time
+}
+
+type idempotencyEntry struct {
+	done   chan struct{}
+	res    []byte
+	expiry time.Time
+}
+
+// NewMemoryIdempotencyCache returns a cache which deduplicates requests handled by the same server
+func NewMemoryIdempotencyCache() IdempotencyCache {
+	return &memoryIdempotencyCache{
+		entries: make(map[string]*idempotencyEntry),
+	}
+}
+
+func (c *memoryIdempotencyCache) Reserve(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.After(c.nextSweep) {
+		for k, e := range c.entries {
+			if now.After(e.expiry) {
+				c.evict(k, e)
+			}
+		}
+		c.nextSweep = now.Add(ttl)
+	}
+
+	if e, ok := c.entries[key]; ok {
+		if now.Before(e.expiry) {
+			return false, nil
+		}
+		c.evict(key, e)
+	}
+	c.entries[key] = &idempotencyEntry{
+		done:   make(chan struct{}),
+		expiry: now.Add(ttl),
+	}
+	return true, nil
+}
+
+func (c *memoryIdempotencyCache) Load(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if !ok {
+		return nil, errIdempotencyKeyExpired
+	}
+
+	select {
+	case <-e.done:
+		if e.res == nil {
+			return nil, errIdempotencyKeyExpired
+		}
+		return e.res, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *memoryIdempotencyCache) Store(_ context.Context, key string, res []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok || e.res != nil {
+		return errIdempotencyKeyExpired
+	}
+	e.res = res
+	e.expiry = time.Now().Add(ttl)
+	close(e.done)
+	return nil
+}
+
+func (c *memoryIdempotencyCache) Release(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok && e.res == nil {
+		c.evict(key, e)
+	}
+	return nil
+}
+
+// evict removes an entry, waking requests waiting for a response which will not be stored
+func (c *memoryIdempotencyCache) evict(key string, e *idempotencyEntry) {
+	delete(c.entries, key)
+	if e.res == nil {
+		close(e.done)
+	}
+}
+
+const redisIdempotencyPollInterval = 10 * time.Millisecond
+
+type redisIdempotencyCache struct {
+	rc     redis.UniversalClient
+	prefix string
+}
+
+// NewRedisIdempotencyCache returns a cache shared by every server using the same redis, so duplicates are
+// detected when they are handled by different servers
+func NewRedisIdempotencyCache(rc redis.UniversalClient, prefix string) IdempotencyCache {
+	return &redisIdempotencyCache{
+		rc:     rc,
+		prefix: prefix,
+	}
+}
+
+// Reserve stores an empty value for the key, which is replaced by the response when it is stored
+func (c *redisIdempotencyCache) Reserve(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return c.rc.SetNX(ctx, c.prefix+key, "", ttl).Result()
+}
+
+func (c *redisIdempotencyCache) Load(ctx context.Context, key string) ([]byte, error) {
+	ticker := time.NewTicker(redisIdempotencyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		res, err := c.rc.Get(ctx, c.prefix+key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return nil, errIdempotencyKeyExpired
+		} else if err != nil {
+			return nil, err
+		} else if len(res) != 0 {
+			return res, nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (c *redisIdempotencyCache) Store(ctx context.Context, key string, res []byte, ttl time.Duration) error {
+	return c.rc.Set(ctx, c.prefix+key, res, ttl).Err()
+}
+
+func (c *redisIdempotencyCache) Release(ctx context.Context, key string) error {
+	return c.rc.Del(ctx, c.prefix+key).Err()
+}
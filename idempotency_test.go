@@ -0,0 +1,69 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package psrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryIdempotencyCache(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryIdempotencyCache()
+
+	reserved, err := c.Reserve(ctx, "a", time.Minute)
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	reserved, err = c.Reserve(ctx, "a", time.Minute)
+	require.NoError(t, err)
+	require.False(t, reserved)
+
+	loaded := make(chan []byte, 1)
+	go func() {
+		res, _ := c.Load(ctx, "a")
+		loaded <- res
+	}()
+	require.NoError(t, c.Store(ctx, "a", []byte("res"), time.Minute))
+	require.Equal(t, []byte("res"), <-loaded)
+
+	t.Run("Release", func(t *testing.T) {
+		reserved, err := c.Reserve(ctx, "b", time.Minute)
+		require.NoError(t, err)
+		require.True(t, reserved)
+
+		require.NoError(t, c.Release(ctx, "b"))
+		_, err = c.Load(ctx, "b")
+		require.Error(t, err)
+
+		reserved, err = c.Reserve(ctx, "b", time.Minute)
+		require.NoError(t, err)
+		require.True(t, reserved)
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		reserved, err := c.Reserve(ctx, "c", time.Millisecond)
+		require.NoError(t, err)
+		require.True(t, reserved)
+
+		time.Sleep(5 * time.Millisecond)
+		reserved, err = c.Reserve(ctx, "c", time.Minute)
+		require.NoError(t, err)
+		require.True(t, reserved)
+	})
+}
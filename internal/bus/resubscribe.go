@@ -0,0 +1,235 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bus
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	resubscribeMinBackoff = 100 * time.Millisecond
+	resubscribeMaxBackoff = 10 * time.Second
+)
+
+type SubscriptionState int
+
+const (
+	SubscriptionLost SubscriptionState = iota + 1
+	SubscriptionRestored
+)
+
+func (s SubscriptionState) String() string {
+	switch s {
+	case SubscriptionLost:
+		return "lost"
+	case SubscriptionRestored:
+		return "restored"
+	default:
+		return "invalid"
+	}
+}
+
+// SubscriptionStateFunc is called when a subscription is lost, and again once it has been restored
+type SubscriptionStateFunc func(channel string, state SubscriptionState)
+
+// SubscribeResilient subscribes to a channel, and resubscribes with backoff if the subscription fails.
+// The channel is only closed when the subscription is closed or ctx is done.
+func SubscribeResilient[MessageType proto.Message](
+	ctx context.Context,
+	bus MessageBus,
+	channel Channel,
+	channelSize int,
+	onState SubscriptionStateFunc,
+) (Subscription[MessageType], error) {
+	return newResilientSubscription(ctx, channel, channelSize, onState, func() (Subscription[MessageType], error) {
+		return Subscribe[MessageType](ctx, bus, channel, channelSize)
+	})
+}
+
+// SubscribeQueueResilient is SubscribeResilient for queue subscriptions
+func SubscribeQueueResilient[MessageType proto.Message](
+	ctx context.Context,
+	bus MessageBus,
+	channel Channel,
+	channelSize int,
+	onState SubscriptionStateFunc,
+) (Subscription[MessageType], error) {
+	return newResilientSubscription(ctx, channel, channelSize, onState, func() (Subscription[MessageType], error) {
+		return SubscribeQueue[MessageType](ctx, bus, channel, channelSize)
+	})
+}
+
+type resilientSubscription[MessageType proto.Message] struct {
+	ctx       context.Context
+	name      string
+	subscribe func() (Subscription[MessageType], error)
+	onState   SubscriptionStateFunc
+	c         chan MessageType
+
+	mu        sync.Mutex
+	sub       Subscription[MessageType]
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func newResilientSubscription[MessageType proto.Message](
+	ctx context.Context,
+	channel Channel,
+	channelSize int,
+	onState SubscriptionStateFunc,
+	subscribe func() (Subscription[MessageType], error),
+) (Subscription[MessageType], error) {
+	sub, err := subscribe()
+	if err != nil {
+		return nil, err
+	}
+
+	name := channel.Server
+	if name == "" {
+		name = channel.Legacy
+	}
+
+	s := &resilientSubscription[MessageType]{
+		ctx:       ctx,
+		name:      name,
+		subscribe: subscribe,
+		onState:   onState,
+		c:         make(chan MessageType, channelSize),
+		sub:       sub,
+		done:      make(chan struct{}),
+	}
+	go s.run(sub)
+	return s, nil
+}
+
+func (s *resilientSubscription[MessageType]) run(sub Subscription[MessageType]) {
+	defer close(s.c)
+
+	for {
+		if !s.forward(sub) {
+			return
+		}
+
+		s.mu.Lock()
+		_ = sub.Close()
+		s.sub = nil
+		s.mu.Unlock()
+
+		s.setState(SubscriptionLost)
+		if sub = s.resubscribe(); sub == nil {
+			return
+		}
+		s.setState(SubscriptionRestored)
+	}
+}
+
+// forward copies messages from sub until it fails, returning false if the subscription was closed or its
+// context is done instead. The subscription ends rather than being restored once its context is done.
+func (s *resilientSubscription[MessageType]) forward(sub Subscription[MessageType]) bool {
+	// s.sub is always the subscription being forwarded, so closing s also closes sub
+	for {
+		select {
+		case msg, ok := <-sub.Channel():
+			if !ok {
+				select {
+				case <-s.done:
+					return false
+				case <-s.ctx.Done():
+					_ = s.Close()
+					return false
+				default:
+					return true
+				}
+			}
+			select {
+			case s.c <- msg:
+			case <-s.done:
+				return false
+			case <-s.ctx.Done():
+				_ = s.Close()
+				return false
+			}
+		case <-s.done:
+			return false
+		case <-s.ctx.Done():
+			_ = s.Close()
+			return false
+		}
+	}
+}
+
+// resubscribe retries with jittered exponential backoff until it succeeds, or returns nil if the subscription
+// is closed or its context is done first
+func (s *resilientSubscription[MessageType]) resubscribe() Subscription[MessageType] {
+	backoff := resubscribeMinBackoff
+	for {
+		wait := time.NewTimer(backoff/2 + time.Duration(rand.Int63n(int64(backoff/2))))
+		select {
+		case <-s.done:
+			wait.Stop()
+			return nil
+		case <-s.ctx.Done():
+			wait.Stop()
+			return nil
+		case <-wait.C:
+		}
+
+		sub, err := s.subscribe()
+		if err == nil {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			select {
+			case <-s.done:
+				_ = sub.Close()
+				return nil
+			default:
+				s.sub = sub
+				return sub
+			}
+		}
+
+		backoff = min(backoff*2, resubscribeMaxBackoff)
+	}
+}
+
+func (s *resilientSubscription[MessageType]) setState(state SubscriptionState) {
+	if s.onState != nil {
+		s.onState(s.name, state)
+	}
+}
+
+func (s *resilientSubscription[MessageType]) Channel() <-chan MessageType {
+	return s.c
+}
+
+func (s *resilientSubscription[MessageType]) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		close(s.done)
+		if s.sub != nil {
+			err = s.sub.Close()
+		}
+	})
+	return err
+}
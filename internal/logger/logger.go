@@ -22,6 +22,10 @@ func SetLogger(l logr.Logger) {
 	logger = l
 }
 
+func Info(msg string, values ...interface{}) {
+	logger.Info(msg, values...)
+}
+
 func Error(err error, msg string, values ...interface{}) {
 	logger.Error(err, msg, values...)
 }
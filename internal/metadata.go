@@ -0,0 +1,64 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"github.com/livekit/psrpc/pkg/metadata"
+)
+
+// EncodeMetadata splits metadata into the string map understood by every version of psrpc, and the
+// repeated and binary values which need the multi-valued encoding. text keys with several values also
+// send their first value as a string.
+func EncodeMetadata(md metadata.MD) (map[string]string, map[string]*MetadataValues) {
+	if md == nil {
+		return nil, nil
+	}
+
+	var values map[string]*MetadataValues
+	for k, vs := range md {
+		if len(vs) == 1 && !metadata.IsBinaryKey(k) {
+			continue
+		}
+		if values == nil {
+			values = make(map[string]*MetadataValues)
+		}
+		v := &MetadataValues{Values: make([][]byte, len(vs))}
+		for i, s := range vs {
+			v.Values[i] = []byte(s)
+		}
+		values[k] = v
+	}
+	return md.Strings(), values
+}
+
+// DecodeMetadata combines the string and multi-valued metadata encoded by EncodeMetadata
+func DecodeMetadata(strs map[string]string, values map[string]*MetadataValues) metadata.MD {
+	md := metadata.FromMetadata(strs)
+	if len(values) == 0 {
+		return md
+	}
+
+	if md == nil {
+		md = metadata.MD{}
+	}
+	for k, v := range values {
+		vs := make([]string, len(v.Values))
+		for i, b := range v.Values {
+			vs[i] = string(b)
+		}
+		md[k] = vs
+	}
+	return md
+}
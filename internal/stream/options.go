@@ -16,6 +16,7 @@ package stream
 
 import (
 	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/metadata"
 )
 
 func getStreamOpts(options psrpc.StreamOpts, opts ...psrpc.StreamOption) psrpc.StreamOpts {
@@ -27,3 +28,69 @@ func getStreamOpts(options psrpc.StreamOpts, opts ...psrpc.StreamOption) psrpc.S
 	}
 	return *o
 }
+
+type Option func(*config)
+
+type config struct {
+	blockingRecv bool
+	recvWindow   int
+	sendWindow   int
+	keepalive    psrpc.KeepaliveOpts
+	resumeToken  string
+	replay       bool
+	responseMD   *metadata.ResponseMetadata
+}
+
+// WithBlockingRecv makes the stream wait for room in its receive channel instead of failing with
+// ErrSlowConsumer. The sender's acks are delayed until the message is buffered, so it slows to the
+// rate of the consumer.
+func WithBlockingRecv() Option {
+	return func(c *config) {
+		c.blockingRecv = true
+	}
+}
+
+// WithRecvWindow enables credit based flow control for received messages. The peer may send up to
+// window messages ahead of the consumer, and is granted more credits as they are delivered.
+func WithRecvWindow(window int) Option {
+	return func(c *config) {
+		c.recvWindow = window
+	}
+}
+
+// WithSendWindow sets the initial credits granted by the peer's receive window. Without it, sends wait
+// for an ack unless the peer grants credits when acknowledging the open request.
+func WithSendWindow(window int) Option {
+	return func(c *config) {
+		c.sendWindow = window
+	}
+}
+
+// WithKeepalive pings the peer at the configured interval, closing the stream when it stops responding
+func WithKeepalive(opts psrpc.KeepaliveOpts) Option {
+	return func(c *config) {
+		c.keepalive = opts
+	}
+}
+
+// WithResumeToken sets the token identifying a resumable stream
+func WithResumeToken(token string) Option {
+	return func(c *config) {
+		c.resumeToken = token
+	}
+}
+
+// WithReplay keeps sent messages until the peer acknowledges them, so they can be replayed on a new stream
+func WithReplay() Option {
+	return func(c *config) {
+		c.replay = true
+	}
+}
+
+// WithResponseMetadata sets the header and trailer exchanged when the server closes the stream. The server's
+// metadata is sent with its close, and the client's is filled in when it receives the close.
+func WithResponseMetadata(md *metadata.ResponseMetadata) Option {
+	return func(c *config) {
+		c.responseMD = md
+	}
+}
@@ -17,9 +17,13 @@ package stream
 import (
 	"context"
 	"errors"
+	"math"
 	"sync"
 	"time"
 
+	"github.com/gammazero/deque"
+	"go.uber.org/atomic"
+	"golang.org/x/exp/slices"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/livekit/psrpc"
@@ -31,12 +35,21 @@ import (
 	"github.com/livekit/psrpc/pkg/rand"
 )
 
+// reorderBufferSize is the number of frames held while waiting for a missing frame
+const reorderBufferSize = 128
+
 type Stream[SendType, RecvType proto.Message] interface {
 	psrpc.ServerStream[SendType, RecvType]
 
 	Ack(context.Context, *internal.Stream) error
 	HandleStream(is *internal.Stream) error
 	Hijacked() bool
+
+	// Unacked returns the payloads of sent messages which the peer has not acknowledged, in the order they
+	// were sent. It is only tracked for streams created WithReplay.
+	Unacked() [][]byte
+	// Replay sends payloads saved from a previous stream
+	Replay(payloads [][]byte) error
 }
 
 type StreamAdapter interface {
@@ -44,6 +57,11 @@ type StreamAdapter interface {
 	Close(streamID string)
 }
 
+type replayEntry struct {
+	seq     uint64
+	payload []byte
+}
+
 type stream[SendType, RecvType proto.Message] struct {
 	*streamBase[SendType, RecvType]
 
@@ -53,6 +71,7 @@ type stream[SendType, RecvType proto.Message] struct {
 
 type streamBase[SendType, RecvType proto.Message] struct {
 	psrpc.StreamOpts
+	config
 
 	ctx      context.Context
 	cancel   context.CancelFunc
@@ -65,7 +84,35 @@ type streamBase[SendType, RecvType proto.Message] struct {
 	pending sync.WaitGroup
 	acks    map[string]chan struct{}
 	closed  bool
+	done    chan struct{}
 	err     error
+
+	// half-close
+	sendClosed bool
+	recvClosed bool
+	recvOnce   sync.Once
+
+	// credit based flow control
+	windowed     bool
+	credits      int
+	creditsReady chan struct{}
+	queue        deque.Deque[RecvType]
+	queueReady   chan struct{}
+	pumpDone     chan struct{}
+
+	// keepalive
+	lastSeen atomic.Int64
+
+	// replay buffer of unacked messages
+	replay []replayEntry
+
+	// ordered delivery
+	sendSeq   uint64
+	reorderMu sync.Mutex
+	recvSeq   uint64
+	reorder   map[uint64]*internal.Stream
+	gapTimer  *time.Timer
+	skipped   atomic.Uint64
 }
 
 func NewStream[SendType, RecvType proto.Message](
@@ -77,28 +124,62 @@ func NewStream[SendType, RecvType proto.Message](
 	streamInterceptors []psrpc.StreamInterceptor,
 	recvChan chan RecvType,
 	acks map[string]chan struct{},
+	opts ...Option,
 ) Stream[SendType, RecvType] {
 
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	// windowed receivers hand messages to the application one at a time, so the window bounds the messages
+	// buffered by the stream, and credits are only granted once a message has been read
+	if c.recvWindow > 0 {
+		recvChan = make(chan RecvType)
+	}
+
 	ctx, cancel := context.WithCancel(ctx)
 	base := &streamBase[SendType, RecvType]{
 		StreamOpts: psrpc.StreamOpts{Timeout: timeout},
+		config:     c,
 		ctx:        ctx,
 		cancel:     cancel,
 		streamID:   streamID,
 		adapter:    adapter,
 		recvChan:   recvChan,
 		acks:       acks,
+		done:       make(chan struct{}),
+		reorder:    make(map[uint64]*internal.Stream),
+
+		windowed:     c.sendWindow > 0,
+		credits:      c.sendWindow,
+		creditsReady: make(chan struct{}, 1),
 	}
 
-	return &stream[SendType, RecvType]{
+	if c.recvWindow > 0 {
+		base.queueReady = make(chan struct{}, 1)
+		base.pumpDone = make(chan struct{})
+		go base.pump()
+	}
+
+	s := &stream[SendType, RecvType]{
 		streamBase: base,
 		handler: interceptors.ChainClientInterceptors[psrpc.StreamHandler](
 			streamInterceptors, i, base,
 		),
 	}
+
+	if c.keepalive.Interval > 0 {
+		base.lastSeen.Store(time.Now().UnixNano())
+		go s.keepalive()
+	}
+
+	return s
 }
 
 func (s *stream[SendType, RecvType]) HandleStream(is *internal.Stream) error {
+	s.lastSeen.Store(time.Now().UnixNano())
+
 	switch b := is.Body.(type) {
 	case *internal.Stream_Ack:
 		s.mu.Lock()
@@ -106,10 +187,54 @@ func (s *stream[SendType, RecvType]) HandleStream(is *internal.Stream) error {
 		delete(s.acks, is.RequestId)
 		s.mu.Unlock()
 
+		// grant credits before releasing the open request, so the first send is windowed
+		if b.Ack.Window > 0 {
+			s.grant(int(b.Ack.Window))
+		}
 		if ok {
 			close(ack)
 		}
 
+	case *internal.Stream_Credit:
+		s.releaseCredits(int(b.Credit.Credits))
+		s.grant(int(b.Credit.Credits))
+
+	case *internal.Stream_Ping:
+		ctx, cancel := context.WithDeadline(s.ctx, time.Unix(0, is.Expiry))
+		defer cancel()
+		if err := s.Ack(ctx, is); err != nil {
+			return err
+		}
+
+	case *internal.Stream_Message, *internal.Stream_CloseSend:
+		return s.handleOrdered(is)
+
+	case *internal.Stream_Close:
+		cause := psrpc.NewErrorFromResponse(b.Close.Code, b.Close.Error)
+		if err := s.setClosed(cause); err != nil {
+			return err
+		}
+
+		if s.responseMD != nil {
+			s.responseMD.Header = internal.DecodeMetadata(b.Close.Header, b.Close.HeaderValues)
+			s.responseMD.Trailer = internal.DecodeMetadata(b.Close.Trailer, b.Close.TrailerValues)
+		}
+
+		s.adapter.Close(s.streamID)
+		s.cancel()
+		s.stopReorder()
+		s.closeRecv(true)
+
+		// interceptors see the peer's close too. the stream is already closed, so nothing is sent
+		_ = s.handler.Close(cause)
+	}
+
+	return nil
+}
+
+// handleFrame delivers a message or half-close from the peer
+func (s *stream[SendType, RecvType]) handleFrame(is *internal.Stream) error {
+	switch b := is.Body.(type) {
 	case *internal.Stream_Message:
 		if err := s.addPending(); err != nil {
 			return err
@@ -118,6 +243,7 @@ func (s *stream[SendType, RecvType]) HandleStream(is *internal.Stream) error {
 
 		v, err := bus.DeserializePayload[RecvType](b.Message.RawMessage)
 		if err != nil {
+			s.returnCredit()
 			err = psrpc.NewError(psrpc.MalformedRequest, err)
 			go func() {
 				if e := s.handler.Close(err); e != nil {
@@ -128,29 +254,141 @@ func (s *stream[SendType, RecvType]) HandleStream(is *internal.Stream) error {
 		}
 
 		if err := s.handler.Recv(v); err != nil {
+			s.returnCredit()
 			return err
 		}
 
+		// windowed receivers grant credits as messages are consumed instead of acking each one
+		if s.recvWindow > 0 {
+			return nil
+		}
+
 		ctx, cancel := context.WithDeadline(s.ctx, time.Unix(0, is.Expiry))
 		defer cancel()
 		if err := s.Ack(ctx, is); err != nil {
 			return err
 		}
 
-	case *internal.Stream_Close:
-		cause := psrpc.NewErrorFromResponse(b.Close.Code, b.Close.Error)
-		if err := s.setClosed(cause); err != nil {
+	case *internal.Stream_CloseSend:
+		s.setRecvClosed()
+
+		ctx, cancel := context.WithDeadline(s.ctx, time.Unix(0, is.Expiry))
+		defer cancel()
+		if err := s.Ack(ctx, is); err != nil {
 			return err
 		}
-
-		s.adapter.Close(s.streamID)
-		s.cancel()
-		close(s.recvChan)
 	}
 
 	return nil
 }
 
+// handleOrdered processes sequenced frames in the order they were sent. frames which arrive early are
+// held until the gap before them is filled, and frames which were already processed are dropped.
+func (s *stream[SendType, RecvType]) handleOrdered(is *internal.Stream) error {
+	if is.Seq == 0 {
+		return s.handleFrame(is)
+	}
+
+	s.reorderMu.Lock()
+	defer s.reorderMu.Unlock()
+
+	// a resent frame replaces the held one, so the ack sent once it is delivered reaches the latest attempt
+	if _, ok := s.reorder[is.Seq]; ok {
+		s.reorder[is.Seq] = is
+		return nil
+	}
+	if is.Seq > s.recvSeq+1 && len(s.reorder) >= reorderBufferSize {
+		s.skipGap()
+	}
+
+	switch {
+	case is.Seq <= s.recvSeq:
+		return s.ackDuplicate(is)
+
+	case is.Seq > s.recvSeq+1:
+		s.reorder[is.Seq] = is
+		if s.gapTimer == nil {
+			s.gapTimer = time.AfterFunc(s.Timeout, s.onGapTimeout)
+		}
+		return nil
+	}
+
+	err := s.handleFrame(is)
+	s.recvSeq = is.Seq
+	s.flushReorder()
+	return err
+}
+
+// ackDuplicate acknowledges a frame again in case the original ack was lost, without delivering it
+func (s *stream[SendType, RecvType]) ackDuplicate(is *internal.Stream) error {
+	if is.GetMessage() != nil && s.recvWindow > 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithDeadline(s.ctx, time.Unix(0, is.Expiry))
+	defer cancel()
+	return s.Ack(ctx, is)
+}
+
+// flushReorder delivers buffered frames which are now next in sequence
+func (s *stream[SendType, RecvType]) flushReorder() {
+	for {
+		next, ok := s.reorder[s.recvSeq+1]
+		if !ok {
+			break
+		}
+		delete(s.reorder, next.Seq)
+		if err := s.handleFrame(next); err != nil {
+			logger.Error(err, "failed to handle request", "requestID", next.RequestId)
+		}
+		s.recvSeq = next.Seq
+	}
+
+	if len(s.reorder) == 0 && s.gapTimer != nil {
+		s.gapTimer.Stop()
+		s.gapTimer = nil
+	}
+}
+
+// skipGap gives up on missing frames, resuming delivery from the earliest buffered frame
+func (s *stream[SendType, RecvType]) skipGap() {
+	first := uint64(math.MaxUint64)
+	for seq := range s.reorder {
+		first = min(first, seq)
+	}
+	logger.Info("skipping missing stream messages", "streamID", s.streamID, "count", first-s.recvSeq-1)
+	s.skipped.Add(first - s.recvSeq - 1)
+	s.recvSeq = first - 1
+	s.flushReorder()
+}
+
+// stopReorder discards frames waiting for a gap to be filled. it holds the reorder lock so that no frame
+// is being delivered when the receive channel closes.
+func (s *streamBase[SendType, RecvType]) stopReorder() {
+	s.reorderMu.Lock()
+	defer s.reorderMu.Unlock()
+
+	if s.gapTimer != nil {
+		s.gapTimer.Stop()
+		s.gapTimer = nil
+	}
+	clear(s.reorder)
+}
+
+func (s *stream[SendType, RecvType]) onGapTimeout() {
+	s.reorderMu.Lock()
+	defer s.reorderMu.Unlock()
+
+	s.gapTimer = nil
+	if len(s.reorder) == 0 || s.ctx.Err() != nil {
+		return
+	}
+	s.skipGap()
+	if len(s.reorder) != 0 && s.gapTimer == nil {
+		s.gapTimer = time.AfterFunc(s.Timeout, s.onGapTimeout)
+	}
+}
+
 func (s *stream[SendType, RecvType]) Context() context.Context {
 	return s.ctx
 }
@@ -159,14 +397,20 @@ func (s *stream[SendType, RecvType]) Channel() <-chan RecvType {
 	return s.recvChan
 }
 
+// Ack acknowledges a message or open request. Acks for open requests carry the receive window.
 func (s *stream[SendType, RecvType]) Ack(ctx context.Context, is *internal.Stream) error {
+	ack := &internal.StreamAck{}
+	if is.GetOpen() != nil {
+		ack.Window = uint32(s.recvWindow)
+	}
+
 	return s.adapter.Send(ctx, &internal.Stream{
 		StreamId:  is.StreamId,
 		RequestId: is.RequestId,
 		SentAt:    is.SentAt,
 		Expiry:    is.Expiry,
 		Body: &internal.Stream_Ack{
-			Ack: &internal.StreamAck{},
+			Ack: ack,
 		},
 	})
 }
@@ -176,6 +420,31 @@ func (s *stream[SendType, RecvType]) Recv(msg proto.Message) error {
 }
 
 func (s *streamBase[SendType, RecvType]) Recv(msg proto.Message) error {
+	s.mu.Lock()
+	recvClosed := s.recvClosed
+	s.mu.Unlock()
+	if recvClosed {
+		return psrpc.ErrStreamSendClosed
+	}
+
+	if s.recvWindow > 0 {
+		return s.enqueue(msg.(RecvType))
+	}
+
+	if s.blockingRecv {
+		timer := time.NewTimer(s.Timeout)
+		defer timer.Stop()
+
+		select {
+		case s.recvChan <- msg.(RecvType):
+		case <-timer.C:
+			return psrpc.ErrSlowConsumer
+		case <-s.done:
+			return s.Err()
+		}
+		return nil
+	}
+
 	select {
 	case s.recvChan <- msg.(RecvType):
 	default:
@@ -194,7 +463,12 @@ func (s *streamBase[SendType, RecvType]) Send(msg proto.Message, opts ...psrpc.S
 	}
 	defer s.pending.Done()
 
-	o := getStreamOpts(s.StreamOpts, opts...)
+	s.mu.Lock()
+	sendClosed := s.sendClosed
+	s.mu.Unlock()
+	if sendClosed {
+		return psrpc.ErrStreamSendClosed
+	}
 
 	b, err := bus.SerializePayload(msg)
 	if err != nil {
@@ -202,8 +476,146 @@ func (s *streamBase[SendType, RecvType]) Send(msg proto.Message, opts ...psrpc.S
 		return
 	}
 
-	ackChan := make(chan struct{})
+	return s.sendPayload(b, getStreamOpts(s.StreamOpts, opts...))
+}
+
+func (s *streamBase[SendType, RecvType]) sendPayload(b []byte, o psrpc.StreamOpts) (err error) {
+	now := time.Now()
+	deadline := now.Add(o.Timeout)
+
+	ctx, cancel := context.WithDeadline(s.ctx, deadline)
+	defer cancel()
+
 	requestID := rand.NewRequestID()
+	is := &internal.Stream{
+		StreamId:  s.streamID,
+		RequestId: requestID,
+		SentAt:    now.UnixNano(),
+		Expiry:    deadline.UnixNano(),
+		Body: &internal.Stream_Message{
+			Message: &internal.StreamMessage{
+				RawMessage: b,
+			},
+		},
+	}
+
+	// with credits from the peer, sends are pipelined up to its receive window
+	if s.isWindowed() {
+		if err = s.acquireCredit(ctx); err != nil {
+			return
+		}
+		is.Seq = s.takeSeq(b, o.Seq)
+		if err = s.adapter.Send(ctx, is); err != nil {
+			s.returnSeq(is.Seq, o.Seq)
+			s.grant(1)
+		}
+		return
+	}
+
+	is.Seq = s.takeSeq(b, o.Seq)
+	published, err := s.sendWithAck(ctx, is)
+	if err != nil {
+		if !published {
+			s.returnSeq(is.Seq, o.Seq)
+		}
+		return
+	}
+	s.releaseSeq(is.Seq)
+	return
+}
+
+// takeSeq numbers a message, or reuses the number from an earlier attempt to send it which may have reached
+// the peer
+func (s *streamBase[SendType, RecvType]) takeSeq(payload []byte, seq *uint64) uint64 {
+	if seq != nil && *seq != 0 {
+		return *seq
+	}
+	n := s.nextSeq(payload)
+	if seq != nil {
+		*seq = n
+	}
+	return n
+}
+
+// returnSeq gives back the number of a message which was never published, so it doesn't leave a gap. once a
+// later message has been numbered it is kept instead, and the message's next attempt fills the gap.
+func (s *streamBase[SendType, RecvType]) returnSeq(n uint64, seq *uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n != s.sendSeq {
+		return
+	}
+	s.sendSeq--
+	s.replay = slices.DeleteFunc(s.replay, func(e replayEntry) bool { return e.seq == n })
+	if seq != nil {
+		*seq = 0
+	}
+}
+
+// nextSeq numbers messages and half-closes, so the peer can deliver them in order. message payloads are
+// kept for replay until the peer acknowledges them.
+func (s *streamBase[SendType, RecvType]) nextSeq(payload []byte) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sendSeq++
+	if s.config.replay && payload != nil {
+		s.replay = append(s.replay, replayEntry{seq: s.sendSeq, payload: payload})
+	}
+	return s.sendSeq
+}
+
+// releaseSeq drops an acknowledged message from the replay buffer
+func (s *streamBase[SendType, RecvType]) releaseSeq(seq uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replay = slices.DeleteFunc(s.replay, func(e replayEntry) bool { return e.seq == seq })
+}
+
+// releaseCredits drops messages from the replay buffer as credits are returned for them. messages are
+// delivered in order, so each credit acknowledges the oldest message.
+func (s *streamBase[SendType, RecvType]) releaseCredits(credits int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replay = s.replay[min(credits, len(s.replay)):]
+}
+
+func (s *streamBase[SendType, RecvType]) Unacked() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	payloads := make([][]byte, 0, len(s.replay))
+	for _, e := range s.replay {
+		payloads = append(payloads, e.payload)
+	}
+	return payloads
+}
+
+func (s *streamBase[SendType, RecvType]) Replay(payloads [][]byte) error {
+	for _, b := range payloads {
+		if err := s.addPending(); err != nil {
+			return err
+		}
+		err := s.sendPayload(b, s.StreamOpts)
+		s.pending.Done()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *streamBase[SendType, RecvType]) ResumeToken() string {
+	return s.resumeToken
+}
+
+func (s *streamBase[SendType, RecvType]) SkippedMessages() uint64 {
+	return s.skipped.Load()
+}
+
+// sendWithAck sends a message to the peer and waits for it to be acknowledged. published reports whether the
+// message was handed to the bus, in which case it may have reached the peer even if the ack never arrived.
+func (s *streamBase[SendType, RecvType]) sendWithAck(ctx context.Context, is *internal.Stream) (published bool, err error) {
+	requestID := is.RequestId
+	ackChan := make(chan struct{})
 
 	s.mu.Lock()
 	s.acks[requestID] = ackChan
@@ -215,39 +627,314 @@ func (s *streamBase[SendType, RecvType]) Send(msg proto.Message, opts ...psrpc.S
 		s.mu.Unlock()
 	}()
 
+	if err = s.adapter.Send(ctx, is); err != nil {
+		return
+	}
+	published = true
+
+	select {
+	case <-ackChan:
+	case <-ctx.Done():
+		err = s.ctxErr()
+	}
+
+	return
+}
+
+func (s *stream[SendType, RecvType]) CloseSend() error {
+	return s.streamBase.CloseSend()
+}
+
+// CloseSend tells the peer that no more messages will be sent, and waits for it to acknowledge
+func (s *streamBase[SendType, RecvType]) CloseSend() error {
+	if err := s.addPending(); err != nil {
+		return err
+	}
+	defer s.pending.Done()
+
+	s.mu.Lock()
+	if s.sendClosed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.sendClosed = true
+	s.mu.Unlock()
+
 	now := time.Now()
-	deadline := now.Add(o.Timeout)
+	deadline := now.Add(s.Timeout)
 
 	ctx, cancel := context.WithDeadline(s.ctx, deadline)
 	defer cancel()
 
-	err = s.adapter.Send(ctx, &internal.Stream{
+	_, err := s.sendWithAck(ctx, &internal.Stream{
 		StreamId:  s.streamID,
-		RequestId: requestID,
+		RequestId: rand.NewRequestID(),
 		SentAt:    now.UnixNano(),
 		Expiry:    deadline.UnixNano(),
-		Body: &internal.Stream_Message{
-			Message: &internal.StreamMessage{
-				RawMessage: b,
-			},
+		Seq:       s.nextSeq(nil),
+		Body: &internal.Stream_CloseSend{
+			CloseSend: &internal.StreamCloseSend{},
 		},
 	})
-	if err != nil {
+	return err
+}
+
+// setRecvClosed closes the receive channel after the peer's half-close. windowed receivers close it
+// once the pump has delivered every queued message.
+func (s *streamBase[SendType, RecvType]) setRecvClosed() {
+	s.mu.Lock()
+	if s.closed || s.recvClosed {
+		s.mu.Unlock()
+		return
+	}
+	s.recvClosed = true
+	s.mu.Unlock()
+
+	if s.recvWindow == 0 {
+		s.closeRecvChan()
 		return
 	}
 
 	select {
-	case <-ackChan:
-	case <-ctx.Done():
+	case s.queueReady <- struct{}{}:
+	default:
+	}
+}
+
+func (s *streamBase[SendType, RecvType]) closeRecvChan() {
+	s.recvOnce.Do(func() { close(s.recvChan) })
+}
+
+func (s *streamBase[SendType, RecvType]) ctxErr() error {
+	select {
+	case <-s.ctx.Done():
+		return s.Err()
+	default:
+		return psrpc.ErrRequestTimedOut
+	}
+}
+
+func (s *streamBase[SendType, RecvType]) isWindowed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.windowed
+}
+
+// grant adds credits from the peer's receive window
+func (s *streamBase[SendType, RecvType]) grant(credits int) {
+	s.mu.Lock()
+	s.windowed = true
+	s.credits += credits
+	s.mu.Unlock()
+
+	select {
+	case s.creditsReady <- struct{}{}:
+	default:
+	}
+}
+
+func (s *streamBase[SendType, RecvType]) acquireCredit(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		if s.credits > 0 {
+			s.credits--
+			remaining := s.credits
+			s.mu.Unlock()
+
+			// wake the next waiting sender
+			if remaining > 0 {
+				select {
+				case s.creditsReady <- struct{}{}:
+				default:
+				}
+			}
+			return nil
+		}
+		s.mu.Unlock()
+
 		select {
-		case <-s.ctx.Done():
-			err = s.Err()
-		default:
-			err = psrpc.ErrRequestTimedOut
+		case <-s.creditsReady:
+		case <-s.done:
+			return s.Err()
+		case <-ctx.Done():
+			return s.ctxErr()
 		}
 	}
+}
 
-	return
+// enqueue buffers a message received within the window, for the pump to deliver
+func (s *streamBase[SendType, RecvType]) enqueue(msg RecvType) error {
+	s.mu.Lock()
+	if s.queue.Len() >= s.recvWindow {
+		s.mu.Unlock()
+		return psrpc.ErrSlowConsumer
+	}
+	s.queue.PushBack(msg)
+	s.mu.Unlock()
+
+	select {
+	case s.queueReady <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// pump delivers queued messages to the receive channel, granting the peer a credit for each one read by the
+// application. credits are returned in batches of half the window to limit control traffic.
+func (s *streamBase[SendType, RecvType]) pump() {
+	defer close(s.pumpDone)
+
+	batch := (s.recvWindow + 1) / 2
+	var consumed int
+	for {
+		s.mu.Lock()
+		if s.queue.Len() == 0 {
+			recvClosed := s.recvClosed
+			s.mu.Unlock()
+			if recvClosed {
+				s.closeRecvChan()
+				return
+			}
+
+			select {
+			case <-s.queueReady:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+		msg := s.queue.PopFront()
+		s.mu.Unlock()
+
+		select {
+		case s.recvChan <- msg:
+		case <-s.done:
+			s.mu.Lock()
+			s.queue.PushFront(msg)
+			s.mu.Unlock()
+			return
+		}
+
+		if consumed++; consumed >= batch {
+			if err := s.sendCredit(consumed); err != nil {
+				logger.Error(err, "failed to send stream credit")
+			}
+			consumed = 0
+		}
+	}
+}
+
+// returnCredit grants the credit used by a message which was dropped instead of queued, so the peer's window
+// doesn't shrink
+func (s *streamBase[SendType, RecvType]) returnCredit() {
+	if s.recvWindow == 0 {
+		return
+	}
+	if err := s.sendCredit(1); err != nil {
+		logger.Error(err, "failed to send stream credit")
+	}
+}
+
+func (s *streamBase[SendType, RecvType]) sendCredit(credits int) error {
+	now := time.Now()
+	ctx, cancel := context.WithDeadline(s.ctx, now.Add(s.Timeout))
+	defer cancel()
+
+	return s.adapter.Send(ctx, &internal.Stream{
+		StreamId:  s.streamID,
+		RequestId: rand.NewRequestID(),
+		SentAt:    now.UnixNano(),
+		Expiry:    now.Add(s.Timeout).UnixNano(),
+		Body: &internal.Stream_Credit{
+			Credit: &internal.StreamCredit{
+				Credits: uint32(credits),
+			},
+		},
+	})
+}
+
+// closeRecv closes the receive channel. when the peer closed the stream, messages already queued are
+// delivered first, as long as the consumer keeps reading.
+func (s *streamBase[SendType, RecvType]) closeRecv(drain bool) {
+	if s.recvWindow == 0 {
+		s.closeRecvChan()
+		return
+	}
+
+	<-s.pumpDone
+	if drain {
+		go func() {
+			defer s.closeRecvChan()
+
+			timer := time.NewTimer(s.Timeout)
+			defer timer.Stop()
+			for {
+				s.mu.Lock()
+				if s.queue.Len() == 0 {
+					s.mu.Unlock()
+					return
+				}
+				msg := s.queue.PopFront()
+				s.mu.Unlock()
+
+				select {
+				case s.recvChan <- msg:
+					timer.Reset(s.Timeout)
+				case <-timer.C:
+					return
+				}
+			}
+		}()
+		return
+	}
+	s.closeRecvChan()
+}
+
+// keepalive pings the peer each interval, and closes the stream with ErrPeerUnresponsive once nothing
+// has been received from it for the configured number of intervals
+func (s *stream[SendType, RecvType]) keepalive() {
+	interval := s.config.keepalive.Interval
+	misses := s.config.keepalive.Misses
+	if misses <= 0 {
+		misses = psrpc.DefaultKeepaliveMisses
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			if now.Sub(time.Unix(0, s.lastSeen.Load())) > interval*time.Duration(misses) {
+				if err := s.handler.Close(psrpc.ErrPeerUnresponsive); err != nil {
+					logger.Error(err, "failed to close stream")
+				}
+				return
+			}
+
+			if err := s.sendPing(now, interval); err != nil {
+				logger.Error(err, "failed to send stream ping")
+			}
+		}
+	}
+}
+
+func (s *streamBase[SendType, RecvType]) sendPing(now time.Time, interval time.Duration) error {
+	deadline := now.Add(interval)
+	ctx, cancel := context.WithDeadline(s.ctx, deadline)
+	defer cancel()
+
+	return s.adapter.Send(ctx, &internal.Stream{
+		StreamId:  s.streamID,
+		RequestId: rand.NewRequestID(),
+		SentAt:    now.UnixNano(),
+		Expiry:    deadline.UnixNano(),
+		Body: &internal.Stream_Ping{
+			Ping: &internal.StreamPing{},
+		},
+	})
 }
 
 func (s *stream[SendType, RecvType]) Hijack() {
@@ -276,6 +963,11 @@ func (s *streamBase[RequestType, ResponseType]) Close(cause error) error {
 	}
 
 	msg := &internal.StreamClose{}
+	if s.responseMD != nil {
+		msg.Header, msg.HeaderValues = internal.EncodeMetadata(s.responseMD.Header)
+		msg.Trailer, msg.TrailerValues = internal.EncodeMetadata(s.responseMD.Trailer)
+	}
+
 	var e psrpc.Error
 	if errors.As(cause, &e) {
 		msg.Error = e.Error()
@@ -299,7 +991,8 @@ func (s *streamBase[RequestType, ResponseType]) Close(cause error) error {
 	s.pending.Wait()
 	s.adapter.Close(s.streamID)
 	s.cancel()
-	close(s.recvChan)
+	s.stopReorder()
+	s.closeRecv(false)
 
 	return err
 }
@@ -322,6 +1015,7 @@ func (s *streamBase[SendType, RecvType]) setClosed(cause error) error {
 
 	s.closed = true
 	s.err = cause
+	close(s.done)
 	return nil
 }
 
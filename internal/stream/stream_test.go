@@ -16,8 +16,10 @@ package stream
 
 import (
 	"context"
+	"strconv"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.uber.org/atomic"
@@ -26,6 +28,7 @@ import (
 	"github.com/livekit/psrpc"
 	"github.com/livekit/psrpc/internal"
 	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/middleware"
 	"github.com/livekit/psrpc/pkg/rand"
 )
 
@@ -71,6 +74,50 @@ func TestClosePendingSend(t *testing.T) {
 	require.EqualValues(t, psrpc.ErrStreamClosed, err.Load())
 }
 
+type closeRecorder struct {
+	psrpc.StreamHandler
+	causes []error
+}
+
+func (h *closeRecorder) Close(cause error) error {
+	h.causes = append(h.causes, cause)
+	return h.StreamHandler.Close(cause)
+}
+
+func TestPeerCloseInterceptors(t *testing.T) {
+	recorder := &closeRecorder{}
+	adapter := &testStreamAdapter{}
+	s := NewStream[*internal.Request, *internal.Response](
+		context.Background(),
+		&info.RequestInfo{},
+		rand.NewStreamID(),
+		psrpc.DefaultClientTimeout,
+		adapter,
+		[]psrpc.StreamInterceptor{func(_ psrpc.RPCInfo, next psrpc.StreamHandler) psrpc.StreamHandler {
+			recorder.StreamHandler = next
+			return recorder
+		}},
+		make(chan *internal.Response),
+		make(map[string]chan struct{}),
+	)
+
+	require.NoError(t, s.HandleStream(&internal.Stream{
+		Body: &internal.Stream_Close{
+			Close: &internal.StreamClose{
+				Error: psrpc.ErrPeerUnresponsive.Error(),
+				Code:  string(psrpc.ErrPeerUnresponsive.Code()),
+			},
+		},
+	}))
+	require.Len(t, recorder.causes, 1)
+	require.Equal(t, psrpc.ErrPeerUnresponsive.Error(), recorder.causes[0].Error())
+
+	// closing the stream again doesn't send another close to the peer
+	require.EqualValues(t, 0, adapter.sendCalls.Load())
+	require.Error(t, s.Close(nil))
+	require.EqualValues(t, 0, adapter.sendCalls.Load())
+}
+
 type testStreamAdapter struct {
 	sendCalls  atomic.Int32
 	closeCalls atomic.Int32
@@ -125,3 +172,315 @@ func TestClosePanic(t *testing.T) {
 		wg.Wait()
 	}
 }
+
+type pipeStreamAdapter struct {
+	msgs chan *internal.Stream
+}
+
+func newPipeStreamAdapter() *pipeStreamAdapter {
+	return &pipeStreamAdapter{msgs: make(chan *internal.Stream, 100)}
+}
+
+func (a *pipeStreamAdapter) Send(ctx context.Context, msg *internal.Stream) error {
+	msg.Expiry = time.Now().Add(psrpc.DefaultClientTimeout).UnixNano()
+	select {
+	case a.msgs <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (a *pipeStreamAdapter) Close(streamID string) {}
+
+func (a *pipeStreamAdapter) connect(s interface{ HandleStream(*internal.Stream) error }) {
+	go func() {
+		for is := range a.msgs {
+			_ = s.HandleStream(is)
+		}
+	}()
+}
+
+func TestWindowedStream(t *testing.T) {
+	adapterA := newPipeStreamAdapter()
+	adapterB := newPipeStreamAdapter()
+	a := NewStream[*internal.Request, *internal.Response](
+		context.Background(),
+		&info.RequestInfo{},
+		rand.NewStreamID(),
+		psrpc.DefaultClientTimeout,
+		adapterA,
+		nil,
+		make(chan *internal.Response),
+		make(map[string]chan struct{}),
+		WithSendWindow(4),
+	)
+	b := NewStream[*internal.Response, *internal.Request](
+		context.Background(),
+		&info.RequestInfo{},
+		rand.NewStreamID(),
+		psrpc.DefaultClientTimeout,
+		adapterB,
+		nil,
+		make(chan *internal.Request),
+		make(map[string]chan struct{}),
+		WithRecvWindow(4),
+	)
+	adapterA.connect(b)
+	adapterB.connect(a)
+
+	// sends are pipelined up to the window without waiting for the consumer
+	for i := 0; i < 4; i++ {
+		require.NoError(t, a.Send(&internal.Request{}))
+	}
+
+	sent := make(chan error, 1)
+	go func() {
+		sent <- a.Send(&internal.Request{})
+	}()
+	select {
+	case <-sent:
+		t.Fatal("send should wait for credits")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// consuming half the window returns credits to the sender
+	<-b.Channel()
+	<-b.Channel()
+	select {
+	case err := <-sent:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("send did not receive credits")
+	}
+
+	// messages queued before the peer closes are still delivered
+	require.NoError(t, a.Close(nil))
+	received := 0
+	for range b.Channel() {
+		received++
+	}
+	require.Equal(t, 3, received)
+}
+
+type rejectingHandler struct {
+	psrpc.StreamHandler
+	reject   atomic.Bool
+	rejected atomic.Int32
+}
+
+func (h *rejectingHandler) Recv(msg proto.Message) error {
+	if h.reject.Load() {
+		h.rejected.Inc()
+		return psrpc.ErrSlowConsumer
+	}
+	return h.StreamHandler.Recv(msg)
+}
+
+func TestWindowedStreamCredits(t *testing.T) {
+	adapterA := newPipeStreamAdapter()
+	adapterB := newPipeStreamAdapter()
+	a := NewStream[*internal.Request, *internal.Response](
+		context.Background(),
+		&info.RequestInfo{},
+		rand.NewStreamID(),
+		psrpc.DefaultClientTimeout,
+		adapterA,
+		nil,
+		make(chan *internal.Response),
+		make(map[string]chan struct{}),
+		WithSendWindow(2),
+	)
+	rejecting := &rejectingHandler{}
+	b := NewStream[*internal.Response, *internal.Request](
+		context.Background(),
+		&info.RequestInfo{},
+		rand.NewStreamID(),
+		psrpc.DefaultClientTimeout,
+		adapterB,
+		[]psrpc.StreamInterceptor{func(_ psrpc.RPCInfo, next psrpc.StreamHandler) psrpc.StreamHandler {
+			rejecting.StreamHandler = next
+			return rejecting
+		}},
+		make(chan *internal.Request, 10),
+		make(map[string]chan struct{}),
+		WithRecvWindow(2),
+	)
+	adapterA.connect(b)
+	adapterB.connect(a)
+
+	send := func() <-chan error {
+		sent := make(chan error, 1)
+		go func() {
+			sent <- a.Send(&internal.Request{})
+		}()
+		return sent
+	}
+	requireSent := func(sent <-chan error) {
+		t.Helper()
+		select {
+		case err := <-sent:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("send did not receive credits")
+		}
+	}
+
+	// messages which are dropped return their credits
+	rejecting.reject.Store(true)
+	for i := 0; i < 4; i++ {
+		requireSent(send())
+	}
+	require.Eventually(t, func() bool { return rejecting.rejected.Load() == 4 }, time.Second, 10*time.Millisecond)
+	rejecting.reject.Store(false)
+
+	// credits are granted when the application reads, not when messages are buffered
+	requireSent(send())
+	requireSent(send())
+	sent := send()
+	select {
+	case <-sent:
+		t.Fatal("send should wait for the application to read")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	<-b.Channel()
+	requireSent(sent)
+}
+
+func TestOrderedDelivery(t *testing.T) {
+	adapter := &testStreamAdapter{}
+	s := NewStream[*internal.Request, *internal.Response](
+		context.Background(),
+		&info.RequestInfo{},
+		rand.NewStreamID(),
+		100*time.Millisecond,
+		adapter,
+		nil,
+		make(chan *internal.Response, 10),
+		make(map[string]chan struct{}),
+	)
+
+	frame := func(seq uint64) *internal.Stream {
+		b, _ := proto.Marshal(&internal.Response{RequestId: strconv.FormatUint(seq, 10)})
+		return &internal.Stream{
+			Seq:    seq,
+			Expiry: time.Now().Add(time.Second).UnixNano(),
+			Body: &internal.Stream_Message{
+				Message: &internal.StreamMessage{RawMessage: b},
+			},
+		}
+	}
+	recv := func() string {
+		select {
+		case res := <-s.Channel():
+			return res.RequestId
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for message")
+			return ""
+		}
+	}
+
+	// early frames are held until the gap is filled, and duplicates are dropped
+	for _, seq := range []uint64{2, 3, 2, 1, 3, 1} {
+		require.NoError(t, s.HandleStream(frame(seq)))
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		require.Equal(t, id, recv())
+	}
+	require.Empty(t, s.Channel())
+
+	// each delivered frame is acked, and late duplicates are acked again without being delivered
+	require.EqualValues(t, 5, adapter.sendCalls.Load())
+
+	// a gap which is never filled is skipped after the timeout
+	require.NoError(t, s.HandleStream(frame(5)))
+	require.Empty(t, s.Channel())
+	require.Equal(t, "5", recv())
+	require.EqualValues(t, 1, s.SkippedMessages())
+
+	require.NoError(t, s.HandleStream(frame(6)))
+	require.Equal(t, "6", recv())
+	require.EqualValues(t, 1, s.SkippedMessages())
+}
+
+// faultyStreamAdapter passes messages to a pipe unless fault fails or drops them
+type faultyStreamAdapter struct {
+	*pipeStreamAdapter
+	fault func(msg *internal.Stream) (deliver bool, err error)
+}
+
+func (a *faultyStreamAdapter) Send(ctx context.Context, msg *internal.Stream) error {
+	deliver, err := a.fault(msg)
+	if err != nil || !deliver {
+		return err
+	}
+	return a.pipeStreamAdapter.Send(ctx, msg)
+}
+
+func TestSendRetries(t *testing.T) {
+	deliver := func(msg *internal.Stream) (bool, error) { return true, nil }
+
+	test := func(t *testing.T, faultA, faultB func(msg *internal.Stream) (bool, error)) {
+		adapterA := &faultyStreamAdapter{newPipeStreamAdapter(), faultA}
+		adapterB := &faultyStreamAdapter{newPipeStreamAdapter(), faultB}
+		a := NewStream[*internal.Request, *internal.Response](
+			context.Background(),
+			&info.RequestInfo{},
+			rand.NewStreamID(),
+			100*time.Millisecond,
+			adapterA,
+			[]psrpc.StreamInterceptor{middleware.NewStreamRetryInterceptor(middleware.RetryOptions{MaxAttempts: 3})},
+			make(chan *internal.Response, 10),
+			make(map[string]chan struct{}),
+		)
+		b := NewStream[*internal.Response, *internal.Request](
+			context.Background(),
+			&info.RequestInfo{},
+			rand.NewStreamID(),
+			psrpc.DefaultClientTimeout,
+			adapterB,
+			nil,
+			make(chan *internal.Request, 10),
+			make(map[string]chan struct{}),
+		)
+		adapterA.connect(b)
+		adapterB.connect(a)
+
+		require.NoError(t, a.Send(&internal.Request{RequestId: "1"}))
+		require.NoError(t, a.Send(&internal.Request{RequestId: "2"}))
+
+		// each message is delivered once, and the retry leaves no gap to wait out
+		for _, id := range []string{"1", "2"} {
+			select {
+			case req := <-b.Channel():
+				require.Equal(t, id, req.RequestId)
+			case <-time.After(time.Second):
+				t.Fatal("timed out waiting for message")
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		require.Empty(t, b.Channel())
+		require.Zero(t, b.SkippedMessages())
+	}
+
+	t.Run("PublishFailed", func(t *testing.T) {
+		var failed atomic.Bool
+		test(t, func(msg *internal.Stream) (bool, error) {
+			if msg.GetMessage() != nil && failed.CompareAndSwap(false, true) {
+				return false, psrpc.NewErrorf(psrpc.Unavailable, "publish failed")
+			}
+			return true, nil
+		}, deliver)
+	})
+
+	t.Run("AckLost", func(t *testing.T) {
+		var dropped atomic.Bool
+		test(t, deliver, func(msg *internal.Stream) (bool, error) {
+			if msg.GetAck() != nil && dropped.CompareAndSwap(false, true) {
+				return false, nil
+			}
+			return true, nil
+		})
+	})
+}
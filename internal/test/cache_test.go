@@ -0,0 +1,182 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/internal"
+	"github.com/livekit/psrpc/internal/bus"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/metadata"
+	"github.com/livekit/psrpc/pkg/middleware"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+)
+
+func TestResponseCache(t *testing.T) {
+	serviceName := "test_response_cache"
+	b := bus.NewLocalMessageBus()
+
+	s := server.NewRPCServer(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   "server",
+	}, b)
+	t.Cleanup(func() { s.Close(true) })
+
+	var executions atomic.Int32
+	register := func(method string, hint func(ctx context.Context)) {
+		s.RegisterMethod(method, false, false, false, false)
+		require.NoError(t, server.RegisterHandler[*internal.Request, *internal.Response](s, method, nil,
+			func(ctx context.Context, req *internal.Request) (*internal.Response, error) {
+				n := executions.Inc()
+				if hint != nil {
+					hint(ctx)
+				}
+				return &internal.Response{RequestId: req.RequestId, SentAt: int64(n)}, nil
+			},
+			nil,
+		))
+	}
+	register("plain", nil)
+	register("hinted", func(ctx context.Context) { middleware.SetCacheMaxAge(ctx, time.Minute) })
+	register("nostore", middleware.SetNoStore)
+
+	newClient := func(opts middleware.CacheOptions, clientOpts ...psrpc.ClientOption) *client.RPCClient {
+		clientOpts = append(clientOpts, middleware.WithResponseCache(opts))
+		c, err := client.NewRPCClient(&info.ServiceDefinition{
+			Name: serviceName,
+			ID:   rand.NewString(),
+		}, b, clientOpts...)
+		require.NoError(t, err)
+		for _, method := range []string{"plain", "hinted", "nostore"} {
+			c.RegisterMethod(method, false, false, false, false)
+		}
+		return c
+	}
+
+	request := func(c *client.RPCClient, method, id string, opts ...psrpc.RequestOption) *internal.Response {
+		res, err := client.RequestSingle[*internal.Response](context.Background(), c, method, nil,
+			&internal.Request{RequestId: id}, opts...)
+		require.NoError(t, err)
+		require.Equal(t, id, res.RequestId)
+		return res
+	}
+
+	t.Run("Hint", func(t *testing.T) {
+		executions.Store(0)
+		c := newClient(middleware.CacheOptions{})
+
+		request(c, "plain", "a")
+		request(c, "plain", "a")
+		require.Equal(t, int32(2), executions.Load())
+
+		first := request(c, "hinted", "a")
+		var md metadata.ResponseMetadata
+		second := request(c, "hinted", "a", psrpc.WithResponseMetadata(&md))
+		require.Equal(t, int32(3), executions.Load())
+		require.Equal(t, first.SentAt, second.SentAt)
+		require.Equal(t, []string{"max-age=60"}, md.Header.Get(middleware.CacheControlKey))
+
+		request(c, "hinted", "b")
+		require.Equal(t, int32(4), executions.Load())
+	})
+
+	t.Run("NoStore", func(t *testing.T) {
+		executions.Store(0)
+		c := newClient(middleware.CacheOptions{TTL: time.Minute})
+
+		request(c, "plain", "a")
+		request(c, "plain", "a")
+		require.Equal(t, int32(1), executions.Load())
+
+		request(c, "nostore", "a")
+		request(c, "nostore", "a")
+		require.Equal(t, int32(3), executions.Load())
+	})
+
+	t.Run("StaleWhileRevalidate", func(t *testing.T) {
+		executions.Store(0)
+		c := newClient(middleware.CacheOptions{
+			TTL:                  50 * time.Millisecond,
+			StaleWhileRevalidate: time.Minute,
+		})
+
+		first := request(c, "plain", "a")
+		time.Sleep(100 * time.Millisecond)
+
+		stale := request(c, "plain", "a")
+		require.Equal(t, first.SentAt, stale.SentAt)
+		require.Eventually(t, func() bool { return executions.Load() == 2 }, time.Second, 10*time.Millisecond)
+
+		require.Eventually(t, func() bool {
+			return request(c, "plain", "a").SentAt == 2
+		}, time.Second, 10*time.Millisecond)
+		require.Equal(t, int32(2), executions.Load())
+	})
+
+	t.Run("Metadata", func(t *testing.T) {
+		executions.Store(0)
+		c := newClient(middleware.CacheOptions{TTL: time.Minute})
+
+		requestAs := func(token string, opts ...psrpc.RequestOption) {
+			ctx := metadata.NewContextWithOutgoingMetadata(context.Background(), metadata.Metadata{"authorization": token})
+			_, err := client.RequestSingle[*internal.Response](ctx, c, "plain", nil, &internal.Request{RequestId: "a"}, opts...)
+			require.NoError(t, err)
+		}
+
+		requestAs("alice")
+		requestAs("alice")
+		require.Equal(t, int32(1), executions.Load())
+
+		requestAs("bob")
+		require.Equal(t, int32(2), executions.Load())
+
+		requestAs("alice", psrpc.WithTargetServer("server"))
+		require.Equal(t, int32(3), executions.Load())
+	})
+
+	t.Run("Retries", func(t *testing.T) {
+		executions.Store(0)
+		c := newClient(middleware.CacheOptions{TTL: time.Minute}, middleware.WithRPCRetries(middleware.RetryOptions{
+			MaxAttempts: 2,
+		}))
+
+		for i := 0; i < 3; i++ {
+			request(c, "plain", "a")
+		}
+		require.Equal(t, int32(1), executions.Load())
+	})
+
+	t.Run("MaxEntries", func(t *testing.T) {
+		executions.Store(0)
+		c := newClient(middleware.CacheOptions{
+			TTL:        time.Minute,
+			MaxEntries: 1,
+		})
+
+		request(c, "plain", "a")
+		request(c, "plain", "b")
+		request(c, "plain", "a")
+		require.Equal(t, int32(3), executions.Load())
+	})
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/internal"
+	"github.com/livekit/psrpc/internal/bus"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/metadata"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+)
+
+func TestClaimMetadata(t *testing.T) {
+	serviceName := "test_claim_metadata"
+	rpc := "get_server"
+	b := bus.NewLocalMessageBus()
+
+	regions := map[string]string{"a": "us-east", "b": "eu-west"}
+	for id, region := range regions {
+		id, region := id, region
+		s := server.NewRPCServer(&info.ServiceDefinition{
+			Name: serviceName,
+			ID:   id,
+		}, b)
+		t.Cleanup(func() { s.Close(true) })
+
+		s.RegisterMethod(rpc, true, false, true, false)
+		err := server.RegisterHandler[*internal.Request, *internal.Response](s, rpc, nil,
+			func(ctx context.Context, req *internal.Request) (*internal.Response, error) {
+				return &internal.Response{ServerId: id}, nil
+			},
+			server.NewClaimAffinityFunc(func(ctx context.Context, req *internal.Request) (float32, metadata.Metadata) {
+				return 1, metadata.Metadata{"region": region}
+			}),
+		)
+		require.NoError(t, err)
+	}
+
+	c, err := client.NewRPCClient(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, b)
+	require.NoError(t, err)
+	c.RegisterMethod(rpc, true, false, true, false)
+
+	for id, region := range regions {
+		var claims []*psrpc.Claim
+		res, err := client.RequestSingle[*internal.Response](
+			context.Background(), c, rpc, nil, &internal.Request{},
+			psrpc.WithSelectionOpts(psrpc.SelectionOpts{
+				AffinityTimeout: 100 * time.Millisecond,
+				SelectionFunc: func(c []*psrpc.Claim) (string, error) {
+					claims = c
+					for _, claim := range c {
+						if claim.Metadata["region"] == region {
+							return claim.ServerID, nil
+						}
+					}
+					return "", psrpc.ErrNoResponse
+				},
+			}),
+		)
+		require.NoError(t, err)
+		require.Equal(t, id, res.ServerId)
+		require.Len(t, claims, 2)
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/internal"
+	"github.com/livekit/psrpc/internal/bus"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/metadata"
+	"github.com/livekit/psrpc/pkg/middleware"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+)
+
+func TestRequestCoalescing(t *testing.T) {
+	serviceName := "test_coalescing"
+	b := bus.NewLocalMessageBus()
+
+	var executions atomic.Int32
+	release := make(chan struct{})
+	handler := func(ctx context.Context, req *internal.Request) (*internal.Response, error) {
+		executions.Inc()
+		<-release
+		return &internal.Response{RequestId: req.RequestId}, nil
+	}
+
+	for _, id := range []string{"a", "b"} {
+		s := server.NewRPCServer(&info.ServiceDefinition{
+			Name: serviceName,
+			ID:   id,
+		}, b)
+		t.Cleanup(func() { s.Close(true) })
+
+		s.RegisterMethod("unary", false, false, false, true)
+		require.NoError(t, server.RegisterHandler[*internal.Request, *internal.Response](s, "unary", nil, handler, nil))
+		s.RegisterMethod("multi", false, true, false, false)
+		require.NoError(t, server.RegisterHandler[*internal.Request, *internal.Response](s, "multi", nil, handler, nil))
+	}
+
+	c, err := client.NewRPCClient(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, b, middleware.WithRequestCoalescing())
+	require.NoError(t, err)
+	c.RegisterMethod("unary", false, false, false, true)
+	c.RegisterMethod("multi", false, true, false, false)
+
+	t.Run("Unary", func(t *testing.T) {
+		executions.Store(0)
+		release = make(chan struct{})
+
+		var wg sync.WaitGroup
+		responses := make([]*internal.Response, 5)
+		for i := range responses {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				res, err := client.RequestSingle[*internal.Response](context.Background(), c, "unary", nil,
+					&internal.Request{RequestId: "same"})
+				require.NoError(t, err)
+				responses[i] = res
+			}(i)
+		}
+
+		require.Eventually(t, func() bool { return executions.Load() == 1 }, time.Second, 10*time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		require.Equal(t, int32(1), executions.Load())
+		for i, res := range responses {
+			require.Equal(t, "same", res.RequestId)
+			if i > 0 {
+				require.NotSame(t, responses[0], res)
+			}
+		}
+
+		_, err = client.RequestSingle[*internal.Response](context.Background(), c, "unary", nil,
+			&internal.Request{RequestId: "other"})
+		require.NoError(t, err)
+		require.Equal(t, int32(2), executions.Load())
+	})
+
+	t.Run("Metadata", func(t *testing.T) {
+		executions.Store(0)
+		release = make(chan struct{})
+
+		var wg sync.WaitGroup
+		for _, token := range []string{"alice", "bob"} {
+			wg.Add(1)
+			go func(token string) {
+				defer wg.Done()
+				ctx := metadata.NewContextWithOutgoingMetadata(context.Background(), metadata.Metadata{"authorization": token})
+				_, err := client.RequestSingle[*internal.Response](ctx, c, "unary", nil, &internal.Request{RequestId: "same"})
+				require.NoError(t, err)
+			}(token)
+		}
+
+		require.Eventually(t, func() bool { return executions.Load() == 2 }, time.Second, 10*time.Millisecond)
+		close(release)
+		wg.Wait()
+	})
+
+	t.Run("Multi", func(t *testing.T) {
+		executions.Store(0)
+		release = make(chan struct{})
+
+		var wg sync.WaitGroup
+		counts := make([]int, 3)
+		for i := range counts {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				resChan, err := client.RequestMulti[*internal.Response](context.Background(), c, "multi", nil,
+					&internal.Request{RequestId: "same"}, psrpc.WithExpectedResponses(2))
+				require.NoError(t, err)
+				for res := range resChan {
+					require.NoError(t, res.Err)
+					require.Equal(t, "same", res.Result.RequestId)
+					counts[i]++
+				}
+			}(i)
+		}
+
+		require.Eventually(t, func() bool { return executions.Load() == 2 }, time.Second, 10*time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		require.Equal(t, int32(2), executions.Load())
+		require.Equal(t, []int{2, 2, 2}, counts)
+	})
+
+	t.Run("MultiTimeout", func(t *testing.T) {
+		executions.Store(0)
+		release = make(chan struct{})
+
+		leader, err := client.RequestMulti[*internal.Response](context.Background(), c, "multi", nil,
+			&internal.Request{RequestId: "timeout"}, psrpc.WithExpectedResponses(2))
+		require.NoError(t, err)
+		require.Eventually(t, func() bool { return executions.Load() == 2 }, time.Second, 10*time.Millisecond)
+
+		// a caller joining the request still stops at its own timeout
+		start := time.Now()
+		joined, err := client.RequestMulti[*internal.Response](context.Background(), c, "multi", nil,
+			&internal.Request{RequestId: "timeout"}, psrpc.WithExpectedResponses(2), psrpc.WithRequestTimeout(100*time.Millisecond))
+		require.NoError(t, err)
+		for range joined {
+		}
+		require.Less(t, time.Since(start), 500*time.Millisecond)
+
+		close(release)
+		var count int
+		for res := range leader {
+			require.NoError(t, res.Err)
+			count++
+		}
+		require.Equal(t, 2, count)
+		require.Equal(t, int32(2), executions.Load())
+	})
+
+	t.Run("Retries", func(t *testing.T) {
+		executions.Store(0)
+		release = make(chan struct{})
+
+		retrying, err := client.NewRPCClient(&info.ServiceDefinition{
+			Name: serviceName,
+			ID:   rand.NewString(),
+		}, b, middleware.WithRPCRetries(middleware.RetryOptions{MaxAttempts: 2}), middleware.WithRequestCoalescing())
+		require.NoError(t, err)
+		t.Cleanup(retrying.Close)
+		retrying.RegisterMethod("unary", false, false, false, true)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := client.RequestSingle[*internal.Response](context.Background(), retrying, "unary", nil,
+					&internal.Request{RequestId: "same"})
+				require.NoError(t, err)
+			}()
+		}
+
+		require.Eventually(t, func() bool { return executions.Load() == 1 }, time.Second, 10*time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		require.Equal(t, int32(1), executions.Load())
+	})
+}
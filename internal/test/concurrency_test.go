@@ -0,0 +1,144 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/internal"
+	"github.com/livekit/psrpc/internal/bus"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+)
+
+func TestServerConcurrency(t *testing.T) {
+	serviceName := "test_concurrency"
+	b := bus.NewLocalMessageBus()
+
+	e := newExecutionCounter()
+	affinities := map[string]float32{"a": 1, "b": 0.5}
+	servers := map[string]*server.RPCServer{}
+	for _, id := range []string{"a", "b"} {
+		id := id
+		opts := []psrpc.ServerOption{}
+		if id == "a" {
+			opts = append(opts, psrpc.WithServerMaxConcurrency(1))
+		}
+		s := server.NewRPCServer(&info.ServiceDefinition{
+			Name: serviceName,
+			ID:   id,
+		}, b, opts...)
+		t.Cleanup(func() { s.Close(true) })
+		servers[id] = s
+
+		s.RegisterMethod("claimed", true, false, true, false)
+		require.NoError(t, server.RegisterHandler[*internal.Request, *internal.Response](s, "claimed", nil, e.handler(id),
+			func(ctx context.Context, req *internal.Request) float32 {
+				return affinities[id]
+			},
+		))
+	}
+	servers["a"].RegisterMethod("queued", false, false, false, true)
+	require.NoError(t, server.RegisterHandler[*internal.Request, *internal.Response](servers["a"], "queued", nil, e.handler("a"), nil))
+
+	c, err := client.NewRPCClient(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, b, psrpc.WithClientSelectTimeout(100*time.Millisecond))
+	require.NoError(t, err)
+	t.Cleanup(c.Close)
+	c.RegisterMethod("claimed", true, false, true, false)
+	c.RegisterMethod("queued", false, false, false, true)
+
+	res, err := client.RequestSingle[*internal.Response](context.Background(), c, "claimed", nil, &internal.Request{RequestId: "first"})
+	require.NoError(t, err)
+	require.Equal(t, "a", res.ServerId)
+
+	// saturate server a
+	go func() {
+		_, _ = client.RequestSingle[*internal.Response](context.Background(), c, "claimed", nil,
+			&internal.Request{RequestId: "block"}, psrpc.WithTargetServer("a"))
+	}()
+	require.Eventually(t, func() bool { return e.count("block") == 1 }, time.Second, 10*time.Millisecond)
+	t.Cleanup(func() { close(e.release) })
+
+	t.Run("saturated server does not claim", func(t *testing.T) {
+		res, err := client.RequestSingle[*internal.Response](context.Background(), c, "claimed", nil, &internal.Request{RequestId: "claim"})
+		require.NoError(t, err)
+		require.Equal(t, "b", res.ServerId)
+		require.Equal(t, 1, e.count("claim"))
+	})
+
+	t.Run("saturated server rejects requests", func(t *testing.T) {
+		_, err := client.RequestSingle[*internal.Response](context.Background(), c, "queued", nil, &internal.Request{RequestId: "rejected"})
+		var perr psrpc.Error
+		require.ErrorAs(t, err, &perr)
+		require.Equal(t, psrpc.ResourceExhausted, perr.Code())
+		require.Equal(t, 0, e.count("rejected"))
+	})
+}
+
+func TestQueuedClaimExpired(t *testing.T) {
+	serviceName := "test_queued_claim"
+	b := bus.NewLocalMessageBus()
+
+	e := newExecutionCounter()
+	s := server.NewRPCServer(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   "a",
+	}, b, psrpc.WithServerMaxConcurrency(1), psrpc.WithServerQueue(2, 5*time.Second))
+	t.Cleanup(func() { s.Close(true) })
+	s.RegisterMethod("claimed", true, false, true, false)
+	require.NoError(t, server.RegisterHandler[*internal.Request, *internal.Response](s, "claimed", nil, e.handler("a"),
+		func(ctx context.Context, req *internal.Request) float32 {
+			return 1
+		},
+	))
+
+	c, err := client.NewRPCClient(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, b, psrpc.WithClientSelectTimeout(100*time.Millisecond))
+	require.NoError(t, err)
+	t.Cleanup(c.Close)
+	c.RegisterMethod("claimed", true, false, true, false)
+
+	go func() {
+		_, _ = client.RequestSingle[*internal.Response](context.Background(), c, "claimed", nil,
+			&internal.Request{RequestId: "block"}, psrpc.WithTargetServer("a"))
+	}()
+	require.Eventually(t, func() bool { return e.count("block") == 1 }, time.Second, 10*time.Millisecond)
+
+	// the request is queued past the client's selection window, so the client gives up on it
+	_, err = client.RequestSingle[*internal.Response](context.Background(), c, "claimed", nil,
+		&internal.Request{RequestId: "late"}, psrpc.WithRequestTimeout(5*time.Second))
+	require.Error(t, err)
+
+	// once the slot is free, the late request is dropped instead of holding it while waiting to be selected
+	close(e.release)
+	start := time.Now()
+	res, err := client.RequestSingle[*internal.Response](context.Background(), c, "claimed", nil, &internal.Request{RequestId: "next"})
+	require.NoError(t, err)
+	require.Equal(t, "a", res.ServerId)
+	require.Less(t, time.Since(start), time.Second)
+	require.Equal(t, 0, e.count("late"))
+}
@@ -0,0 +1,199 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/internal/bus"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+)
+
+func TestConsume(t *testing.T) {
+	serviceName := "test_consume"
+	b := bus.NewLocalMessageBus()
+
+	s := server.NewRPCServer(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   "server",
+	}, b)
+	t.Cleanup(func() { s.Close(true) })
+	s.RegisterMethod("updates", false, true, false, false)
+
+	c, err := client.NewRPCClient(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, b)
+	require.NoError(t, err)
+	t.Cleanup(c.Close)
+	c.RegisterMethod("updates", false, true, false, false)
+
+	// messages carry an ordering key and a sequence number
+	publish := func(topic, key string, seq int) {
+		msg, err := structpb.NewStruct(map[string]any{"key": key, "seq": seq})
+		require.NoError(t, err)
+		require.NoError(t, s.Publish(context.Background(), "updates", []string{topic}, msg))
+	}
+	messageKey := func(msg proto.Message) string {
+		return msg.(*structpb.Struct).Fields["key"].GetStringValue()
+	}
+
+	t.Run("Ordering", func(t *testing.T) {
+		var mu sync.Mutex
+		seen := map[string][]int64{}
+		var running, maxRunning atomic.Int32
+		var intercepted atomic.Int32
+
+		consumer, err := client.Consume[*structpb.Struct](context.Background(), c, "updates", []string{"ordering"},
+			func(ctx context.Context, req *structpb.Struct) error {
+				if n := running.Inc(); n > maxRunning.Load() {
+					maxRunning.Store(n)
+				}
+				time.Sleep(5 * time.Millisecond)
+				running.Dec()
+
+				mu.Lock()
+				defer mu.Unlock()
+				key := messageKey(req)
+				seen[key] = append(seen[key], int64(req.Fields["seq"].GetNumberValue()))
+				return nil
+			},
+			psrpc.WithConsumeConcurrency(4),
+			psrpc.WithConsumeOrderingKey(messageKey),
+			psrpc.WithConsumeInterceptors(func(info psrpc.RPCInfo, next psrpc.SubscriptionHandler) psrpc.SubscriptionHandler {
+				return func(ctx context.Context, msg proto.Message) error {
+					intercepted.Inc()
+					return next(ctx, msg)
+				}
+			}),
+		)
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+
+		for seq := 0; seq < 10; seq++ {
+			for key := 0; key < 4; key++ {
+				publish("ordering", strconv.Itoa(key), seq)
+			}
+		}
+
+		require.Eventually(t, func() bool { return intercepted.Load() == 40 }, time.Second, 10*time.Millisecond)
+		require.NoError(t, consumer.Close())
+
+		require.LessOrEqual(t, maxRunning.Load(), int32(4))
+		require.Greater(t, maxRunning.Load(), int32(1))
+		for key := 0; key < 4; key++ {
+			require.Equal(t, []int64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, seen[strconv.Itoa(key)])
+		}
+	})
+
+	t.Run("HotKey", func(t *testing.T) {
+		release := make(chan struct{})
+		handled := make(chan string, 10)
+		consumer, err := client.Consume[*structpb.Struct](context.Background(), c, "updates", []string{"hot"},
+			func(ctx context.Context, req *structpb.Struct) error {
+				if messageKey(req) == "hot" {
+					<-release
+				}
+				handled <- messageKey(req)
+				return nil
+			},
+			psrpc.WithConsumeConcurrency(2),
+			psrpc.WithConsumeOrderingKey(messageKey),
+		)
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+
+		// messages waiting behind the busy key don't hold slots, so other keys are still handled
+		for seq := 0; seq < 3; seq++ {
+			publish("hot", "hot", seq)
+		}
+		publish("hot", "cold", 0)
+
+		select {
+		case id := <-handled:
+			require.Equal(t, "cold", id)
+		case <-time.After(time.Second):
+			t.Fatal("cold key stalled behind hot key")
+		}
+
+		close(release)
+		for i := 0; i < 3; i++ {
+			require.Equal(t, "hot", <-handled)
+		}
+		require.NoError(t, consumer.Close())
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		errs := make(chan error, 2)
+		consumer, err := client.Consume[*structpb.Struct](context.Background(), c, "updates", []string{"errors"},
+			func(ctx context.Context, req *structpb.Struct) error {
+				if messageKey(req) == "panic" {
+					panic("handler panic")
+				}
+				return psrpc.NewErrorf(psrpc.InvalidArgument, "invalid")
+			},
+			psrpc.WithConsumePanicRecovery(),
+			psrpc.WithConsumeErrorHandler(func(msg proto.Message, err error) {
+				errs <- err
+			}),
+		)
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+
+		publish("errors", "panic", 0)
+		publish("errors", "error", 1)
+
+		var e psrpc.Error
+		require.ErrorAs(t, <-errs, &e)
+		require.Equal(t, psrpc.Internal, e.Code())
+		require.ErrorAs(t, <-errs, &e)
+		require.Equal(t, psrpc.InvalidArgument, e.Code())
+
+		require.NoError(t, consumer.Close())
+		select {
+		case <-consumer.Done():
+		default:
+			t.Fatal("consumer is not done")
+		}
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		consumer, err := client.Consume[*structpb.Struct](ctx, c, "updates", []string{"context"},
+			func(ctx context.Context, req *structpb.Struct) error { return nil },
+		)
+		require.NoError(t, err)
+
+		cancel()
+		select {
+		case <-consumer.Done():
+		case <-time.After(time.Second):
+			t.Fatal("consumer did not stop")
+		}
+	})
+}
@@ -0,0 +1,178 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/internal"
+	"github.com/livekit/psrpc/internal/bus"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+)
+
+func TestIdempotency(t *testing.T) {
+	serviceName := "test_idempotency"
+	b := bus.NewLocalMessageBus()
+
+	s := server.NewRPCServer(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   "server",
+	}, b, psrpc.WithServerIdempotency(psrpc.IdempotencyOpts{
+		Cache: psrpc.NewMemoryIdempotencyCache(),
+	}))
+	t.Cleanup(func() { s.Close(true) })
+
+	var executions atomic.Int32
+	var fail atomic.Bool
+	unblock := make(chan struct{})
+	close(unblock)
+	var mu sync.Mutex
+
+	s.RegisterMethod("unary", false, false, false, false)
+	require.NoError(t, server.RegisterHandler[*internal.Request, *internal.Response](s, "unary", nil,
+		func(ctx context.Context, req *internal.Request) (*internal.Response, error) {
+			n := executions.Inc()
+			mu.Lock()
+			ch := unblock
+			mu.Unlock()
+			<-ch
+			if fail.CompareAndSwap(true, false) {
+				return nil, psrpc.NewErrorf(psrpc.Unavailable, "unavailable")
+			}
+			return &internal.Response{ServerId: strconv.Itoa(int(n))}, nil
+		},
+		nil,
+	))
+
+	c, err := client.NewRPCClient(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, b)
+	require.NoError(t, err)
+	c.RegisterMethod("unary", false, false, false, false)
+
+	request := func(key string) (*internal.Response, error) {
+		return client.RequestSingle[*internal.Response](context.Background(), c, "unary", nil, &internal.Request{},
+			psrpc.WithIdempotencyKey(key))
+	}
+
+	t.Run("Duplicate", func(t *testing.T) {
+		executions.Store(0)
+		key := rand.NewString()
+
+		first, err := request(key)
+		require.NoError(t, err)
+		second, err := request(key)
+		require.NoError(t, err)
+
+		require.Equal(t, int32(1), executions.Load())
+		require.Equal(t, first.ServerId, second.ServerId)
+
+		_, err = request(rand.NewString())
+		require.NoError(t, err)
+		require.Equal(t, int32(2), executions.Load())
+	})
+
+	t.Run("InFlight", func(t *testing.T) {
+		executions.Store(0)
+		key := rand.NewString()
+
+		mu.Lock()
+		unblock = make(chan struct{})
+		mu.Unlock()
+
+		var wg sync.WaitGroup
+		responses := make([]*internal.Response, 2)
+		for i := range responses {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				res, err := request(key)
+				require.NoError(t, err)
+				responses[i] = res
+			}(i)
+		}
+
+		require.Eventually(t, func() bool { return executions.Load() == 1 }, time.Second, 10*time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		close(unblock)
+		mu.Unlock()
+		wg.Wait()
+
+		require.Equal(t, int32(1), executions.Load())
+		require.Equal(t, responses[0].ServerId, responses[1].ServerId)
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		executions.Store(0)
+		key := rand.NewString()
+
+		fail.Store(true)
+		_, err := request(key)
+		require.Error(t, err)
+
+		_, err = request(key)
+		require.NoError(t, err)
+		require.Equal(t, int32(2), executions.Load())
+	})
+
+	t.Run("FailedInFlight", func(t *testing.T) {
+		executions.Store(0)
+		key := rand.NewString()
+
+		mu.Lock()
+		unblock = make(chan struct{})
+		mu.Unlock()
+		fail.Store(true)
+
+		var wg sync.WaitGroup
+		responses := make(chan *internal.Response, 3)
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if res, err := request(key); err == nil {
+					responses <- res
+				}
+			}()
+		}
+
+		require.Eventually(t, func() bool { return executions.Load() == 1 }, time.Second, 10*time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		close(unblock)
+		mu.Unlock()
+		wg.Wait()
+		close(responses)
+
+		// the first execution fails, and one of the waiting duplicates runs in its place
+		require.Equal(t, int32(2), executions.Load())
+		require.Len(t, responses, 2)
+		first, second := <-responses, <-responses
+		require.Equal(t, first.ServerId, second.ServerId)
+	})
+}
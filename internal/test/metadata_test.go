@@ -0,0 +1,100 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/internal"
+	"github.com/livekit/psrpc/internal/bus"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/metadata"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+)
+
+func TestMultiValuedMetadata(t *testing.T) {
+	serviceName := "test_multi_valued_metadata"
+	b := bus.NewLocalMessageBus()
+
+	heads := make(chan *metadata.Header, 1)
+	s := server.NewRPCServer(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, b)
+	t.Cleanup(func() { s.Close(true) })
+
+	s.RegisterMethod("unary", false, false, false, false)
+	require.NoError(t, server.RegisterHandler[*internal.Request, *internal.Response](s, "unary", nil,
+		func(ctx context.Context, req *internal.Request) (*internal.Response, error) {
+			heads <- metadata.IncomingHeader(ctx)
+			return &internal.Response{}, nil
+		},
+		nil,
+	))
+
+	s.RegisterMethod("stream", false, false, true, false)
+	require.NoError(t, server.RegisterStreamHandler[*internal.Request, *internal.Response](s, "stream", nil,
+		func(stream psrpc.ServerStream[*internal.Response, *internal.Request]) error {
+			heads <- metadata.IncomingHeader(stream.Context())
+			return nil
+		},
+		nil,
+	))
+
+	c, err := client.NewRPCClientWithStreams(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, b)
+	require.NoError(t, err)
+	c.RegisterMethod("unary", false, false, false, false)
+	c.RegisterMethod("stream", false, false, true, false)
+
+	trace := string([]byte{0x00, 0xff, 0x10})
+	ctx := metadata.NewContextWithOutgoingMetadata(context.Background(), metadata.Metadata{"user": "alice"})
+	ctx = metadata.NewContextWithOutgoingMD(ctx, metadata.MD{
+		"scope":     {"read", "write"},
+		"trace-bin": {trace},
+	})
+
+	expected := func(head *metadata.Header) {
+		require.Equal(t, metadata.MD{
+			"user":      {"alice"},
+			"scope":     {"read", "write"},
+			"trace-bin": {trace},
+		}, head.Values)
+
+		// string metadata keeps the first value of each text key
+		require.Equal(t, metadata.Metadata{"user": "alice", "scope": "read"}, head.Metadata)
+	}
+
+	t.Run("Unary", func(t *testing.T) {
+		_, err := client.RequestSingle[*internal.Response](ctx, c, "unary", nil, &internal.Request{})
+		require.NoError(t, err)
+		expected(<-heads)
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		stream, err := client.OpenStream[*internal.Request, *internal.Response](ctx, c, "stream", nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = stream.Close(nil) })
+		expected(<-heads)
+	})
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/internal"
+	"github.com/livekit/psrpc/internal/bus"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+)
+
+func TestMultiRPCExpectedResponses(t *testing.T) {
+	serviceName := "test_multi_expected"
+	rpc := "get_server"
+	b := bus.NewLocalMessageBus()
+
+	for _, id := range []string{"a", "b", "c"} {
+		id := id
+		s := server.NewRPCServer(&info.ServiceDefinition{
+			Name: serviceName,
+			ID:   id,
+		}, b)
+		t.Cleanup(func() { s.Close(true) })
+
+		s.RegisterMethod(rpc, false, true, false, false)
+		err := server.RegisterHandler[*internal.Request, *internal.Response](s, rpc, nil,
+			func(ctx context.Context, req *internal.Request) (*internal.Response, error) {
+				return &internal.Response{ServerId: id}, nil
+			},
+			nil,
+		)
+		require.NoError(t, err)
+	}
+
+	c, err := client.NewRPCClient(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, b)
+	require.NoError(t, err)
+	c.RegisterMethod(rpc, false, true, false, false)
+
+	requestWithContext := func(ctx context.Context, opts ...psrpc.RequestOption) ([]*psrpc.Response[*internal.Response], time.Duration) {
+		start := time.Now()
+		opts = append(opts, psrpc.WithRequestTimeout(time.Second))
+		resChan, err := client.RequestMulti[*internal.Response](ctx, c, rpc, nil, &internal.Request{}, opts...)
+		require.NoError(t, err)
+
+		var responses []*psrpc.Response[*internal.Response]
+		for res := range resChan {
+			responses = append(responses, res)
+		}
+		return responses, time.Since(start)
+	}
+
+	request := func(opts ...psrpc.RequestOption) ([]*psrpc.Response[*internal.Response], time.Duration) {
+		return requestWithContext(context.Background(), opts...)
+	}
+
+	t.Run("Count", func(t *testing.T) {
+		responses, elapsed := request(psrpc.WithExpectedResponses(3))
+		require.Len(t, responses, 3)
+		require.Less(t, elapsed, 500*time.Millisecond)
+	})
+
+	t.Run("Servers", func(t *testing.T) {
+		responses, elapsed := request(psrpc.WithExpectedServers("a", "b", "c"))
+		require.Len(t, responses, 3)
+		require.Less(t, elapsed, 500*time.Millisecond)
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		responses, elapsed := request(psrpc.WithExpectedServers("a", "d", "e"))
+		require.Len(t, responses, 4)
+		require.GreaterOrEqual(t, elapsed, time.Second)
+
+		var missing *psrpc.MissingResponsesError
+		last := responses[len(responses)-1].Err
+		require.True(t, errors.As(last, &missing))
+		require.Equal(t, []string{"d", "e"}, missing.ServerIDs)
+		require.Equal(t, 2, missing.Count)
+		require.True(t, errors.Is(last, psrpc.DeadlineExceeded))
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+		responses, elapsed := requestWithContext(ctx, psrpc.WithExpectedServers("a", "d", "e"))
+		require.Len(t, responses, 4)
+		require.Less(t, elapsed, 500*time.Millisecond)
+
+		var missing *psrpc.MissingResponsesError
+		last := responses[len(responses)-1].Err
+		require.True(t, errors.As(last, &missing))
+		require.Equal(t, []string{"d", "e"}, missing.ServerIDs)
+		require.True(t, errors.Is(last, psrpc.Canceled))
+	})
+}
@@ -71,6 +71,15 @@ func testGeneratedService(t *testing.T, bus func(t testing.TB) bus.MessageBus) {
 	require.Equal(t, 1, requestCount)
 	require.Equal(t, 1, responseCount)
 
+	_, err = psrpc.All(ctx, cB.NormalRPCAsync(ctx, req), cB.NormalRPCAsync(ctx, req))
+	require.NoError(t, err)
+
+	sA.Lock()
+	sB.Lock()
+	require.Equal(t, 3, sA.counts["NormalRPC"]+sB.counts["NormalRPC"])
+	sA.Unlock()
+	sB.Unlock()
+
 	// rpc IntensiveRPC(MyRequest) returns (MyResponse) {
 	//   option (psrpc.options).type = AFFINITY;
 	_, err = cB.IntensiveRPC(ctx, req, psrpc.WithSelectionOpts(psrpc.SelectionOpts{
@@ -127,6 +136,22 @@ func testGeneratedService(t *testing.T, bus func(t testing.TB) bus.MessageBus) {
 	sA.Unlock()
 	sB.Unlock()
 
+	// rpc WatchStats(MyRequest) returns (stream MyResponse);
+	watch, err := cA.WatchStats(ctx, req)
+	require.NoError(t, err)
+	watched := 0
+	for range watch.Channel() {
+		watched++
+	}
+	require.Equal(t, 3, watched)
+	require.EqualError(t, watch.Err(), psrpc.ErrStreamClosed.Error())
+
+	sA.Lock()
+	sB.Lock()
+	require.Equal(t, 1, sA.counts["WatchStats"]+sB.counts["WatchStats"])
+	sA.Unlock()
+	sB.Unlock()
+
 	// rpc GetRegionStats(MyRequest) returns (MyResponse) {
 	//   option (psrpc.options).topics = true;
 	//   option (psrpc.options).type = MULTI;
@@ -153,6 +178,10 @@ func testGeneratedService(t *testing.T, bus func(t testing.TB) bus.MessageBus) {
 	sA.Unlock()
 	sB.Unlock()
 
+	stats, err := psrpc.FirstN(ctx, NewMyServiceGetRegionStatsMultiRequest(cB, "regionB", req), 1)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+
 	// rpc UpdateRegionState(Ignored) returns (MyUpdate) {
 	//   option (psrpc.options).subscription = true;
 	//   option (psrpc.options).topics = true;
@@ -295,6 +324,18 @@ func (s *MyService) ExchangeUpdates(stream psrpc.ServerStream[*MyServerMessage,
 	return nil
 }
 
+func (s *MyService) WatchStats(_ *MyRequest, stream psrpc.SendStream[*MyResponse]) error {
+	s.Lock()
+	s.counts["WatchStats"]++
+	s.Unlock()
+	for i := 0; i < 3; i++ {
+		if err := stream.Send(&MyResponse{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *MyService) GetRegionStats(_ context.Context, _ *MyRequest) (*MyResponse, error) {
 	s.Lock()
 	s.counts["GetRegionStats"]++
@@ -0,0 +1,92 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/internal"
+	"github.com/livekit/psrpc/internal/bus"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/metadata"
+	"github.com/livekit/psrpc/pkg/server"
+)
+
+func TestMetadataPropagation(t *testing.T) {
+	b := bus.NewLocalMessageBus()
+	rpc := "forward"
+
+	// the backend records the header of the request it receives
+	heads := make(chan *metadata.Header, 1)
+	backend := server.NewRPCServer(&info.ServiceDefinition{Name: "backend", ID: "backend"}, b)
+	t.Cleanup(func() { backend.Close(true) })
+	backend.RegisterMethod(rpc, false, false, false, false)
+	require.NoError(t, server.RegisterHandler[*internal.Request, *internal.Response](backend, rpc, nil,
+		func(ctx context.Context, req *internal.Request) (*internal.Response, error) {
+			heads <- metadata.IncomingHeader(ctx)
+			return &internal.Response{}, nil
+		},
+		nil,
+	))
+
+	// the frontend calls the backend from its handler's context
+	backendClient, err := client.NewRPCClient(&info.ServiceDefinition{Name: "backend", ID: "frontend"}, b,
+		psrpc.WithClientMetadataPropagation(metadata.PropagationPolicy{
+			Keys:     []string{"authorization"},
+			Prefixes: []string{"x-"},
+		}),
+	)
+	require.NoError(t, err)
+	backendClient.RegisterMethod(rpc, false, false, false, false)
+
+	frontend := server.NewRPCServer(&info.ServiceDefinition{Name: "frontend", ID: "frontend"}, b)
+	t.Cleanup(func() { frontend.Close(true) })
+	frontend.RegisterMethod(rpc, false, false, false, false)
+	require.NoError(t, server.RegisterHandler[*internal.Request, *internal.Response](frontend, rpc, nil,
+		func(ctx context.Context, req *internal.Request) (*internal.Response, error) {
+			ctx = metadata.AppendMetadataToOutgoingContext(ctx, "x-request-id", "override")
+			return client.RequestSingle[*internal.Response](ctx, backendClient, rpc, nil, req)
+		},
+		nil,
+	))
+
+	c, err := client.NewRPCClient(&info.ServiceDefinition{Name: "frontend", ID: "origin"}, b)
+	require.NoError(t, err)
+	c.RegisterMethod(rpc, false, false, false, false)
+
+	ctx := metadata.NewContextWithOutgoingMetadata(context.Background(), metadata.Metadata{
+		"authorization":  "token",
+		"x-trace-id":     "abc",
+		"x-request-id":   "123",
+		"content-length": "42",
+	})
+	_, err = client.RequestSingle[*internal.Response](ctx, c, rpc, nil, &internal.Request{})
+	require.NoError(t, err)
+
+	head := <-heads
+	require.Equal(t, metadata.Metadata{
+		"authorization": "token",
+		"x-trace-id":    "abc",
+		"x-request-id":  "override",
+	}, head.Metadata)
+	require.Equal(t, "frontend", head.RemoteID)
+	require.Equal(t, []string{"origin"}, head.Route)
+	require.Equal(t, 2, head.Hops())
+}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
 
 	"github.com/livekit/psrpc"
 	"github.com/livekit/psrpc/internal"
@@ -40,6 +41,12 @@ func TestRPC(t *testing.T) {
 		t.Run("Stream", func(t *testing.T) {
 			testStream(t, bus)
 		})
+		t.Run("StickyRouting", func(t *testing.T) {
+			testStickyRouting(t, bus)
+		})
+		t.Run("TargetServer", func(t *testing.T) {
+			testTargetServer(t, bus)
+		})
 	})
 }
 
@@ -213,3 +220,110 @@ func testStream(t *testing.T, bus func(t testing.TB) bus.MessageBus) {
 		t.Fatal("server did not close")
 	}
 }
+
+func testStickyRouting(t *testing.T, bus func(t testing.TB) bus.MessageBus) {
+	serviceName := "test_sticky"
+	rpc := "get_server"
+
+	servers := map[string]*server.RPCServer{}
+	var claims atomic.Int32
+	for _, id := range []string{"a", "b"} {
+		id := id
+		s := server.NewRPCServer(&info.ServiceDefinition{
+			Name: serviceName,
+			ID:   id,
+		}, bus(t))
+		t.Cleanup(func() { s.Close(true) })
+
+		s.RegisterMethod(rpc, true, false, true, false)
+		err := server.RegisterHandler[*internal.Request, *internal.Response](s, rpc, nil,
+			func(ctx context.Context, req *internal.Request) (*internal.Response, error) {
+				return &internal.Response{ServerId: id}, nil
+			},
+			func(ctx context.Context, req *internal.Request) float32 {
+				claims.Inc()
+				return 1
+			},
+		)
+		require.NoError(t, err)
+		servers[id] = s
+	}
+
+	c, err := client.NewRPCClient(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, bus(t), psrpc.WithClientStickyRouting(time.Minute))
+	require.NoError(t, err)
+	c.RegisterMethod(rpc, true, false, true, false)
+	time.Sleep(time.Second)
+
+	ctx := context.Background()
+	res, err := client.RequestSingle[*internal.Response](ctx, c, rpc, nil, &internal.Request{}, psrpc.WithRoutingKey("session"))
+	require.NoError(t, err)
+	selected := res.ServerId
+	require.EqualValues(t, 2, claims.Load())
+
+	for i := 0; i < 3; i++ {
+		res, err = client.RequestSingle[*internal.Response](ctx, c, rpc, nil, &internal.Request{}, psrpc.WithRoutingKey("session"))
+		require.NoError(t, err)
+		require.Equal(t, selected, res.ServerId)
+	}
+	require.EqualValues(t, 5, claims.Load())
+
+	servers[selected].Close(true)
+
+	res, err = client.RequestSingle[*internal.Response](ctx, c, rpc, nil, &internal.Request{}, psrpc.WithRoutingKey("session"))
+	require.NoError(t, err)
+	require.NotEqual(t, selected, res.ServerId)
+}
+
+func testTargetServer(t *testing.T, bus func(t testing.TB) bus.MessageBus) {
+	serviceName := "test_target"
+	rpc := "get_server"
+
+	for _, id := range []string{"a", "b", "c"} {
+		id := id
+		s := server.NewRPCServer(&info.ServiceDefinition{
+			Name: serviceName,
+			ID:   id,
+		}, bus(t))
+		t.Cleanup(func() { s.Close(true) })
+
+		s.RegisterMethod(rpc, false, false, true, false)
+		err := server.RegisterHandler[*internal.Request, *internal.Response](s, rpc, nil,
+			func(ctx context.Context, req *internal.Request) (*internal.Response, error) {
+				return &internal.Response{ServerId: id}, nil
+			},
+			func(ctx context.Context, req *internal.Request) float32 {
+				if id == "c" {
+					return -1
+				}
+				return 1
+			},
+		)
+		require.NoError(t, err)
+	}
+
+	c, err := client.NewRPCClient(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, bus(t))
+	require.NoError(t, err)
+	c.RegisterMethod(rpc, false, false, true, false)
+	time.Sleep(time.Second)
+
+	ctx := context.Background()
+	for _, id := range []string{"a", "b"} {
+		res, err := client.RequestSingle[*internal.Response](ctx, c, rpc, nil, &internal.Request{}, psrpc.WithTargetServer(id))
+		require.NoError(t, err)
+		require.Equal(t, id, res.ServerId)
+	}
+
+	_, err = client.RequestSingle[*internal.Response](ctx, c, rpc, nil, &internal.Request{}, psrpc.WithTargetServer("c"))
+	require.ErrorIs(t, err, psrpc.ErrRequestRejected)
+
+	start := time.Now()
+	_, err = client.RequestSingle[*internal.Response](ctx, c, rpc, nil, &internal.Request{}, psrpc.WithTargetServer("d"))
+	require.ErrorIs(t, err, psrpc.ErrServerNotFound)
+	require.Less(t, time.Since(start), psrpc.DefaultClientTimeout)
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/internal"
+	"github.com/livekit/psrpc/internal/bus"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/metadata"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+)
+
+func TestResponseMetadata(t *testing.T) {
+	serviceName := "test_response_metadata"
+	b := bus.NewLocalMessageBus()
+
+	s := server.NewRPCServer(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   "server",
+	}, b)
+	t.Cleanup(func() { s.Close(true) })
+
+	handler := func(ctx context.Context, req *internal.Request) (*internal.Response, error) {
+		metadata.SetHeader(ctx, metadata.MD{"version": {"1.2.3"}, "set-cookie": {"a"}})
+		metadata.SetHeader(ctx, metadata.MD{"set-cookie": {"b"}, "token-bin": {"\x00\xff"}})
+		metadata.SetTrailer(ctx, metadata.MD{"remaining": {"9"}})
+		return &internal.Response{}, nil
+	}
+
+	s.RegisterMethod("unary", false, false, false, false)
+	require.NoError(t, server.RegisterHandler[*internal.Request, *internal.Response](s, "unary", nil, handler, nil))
+
+	s.RegisterMethod("multi", false, true, false, false)
+	require.NoError(t, server.RegisterHandler[*internal.Request, *internal.Response](s, "multi", nil, handler, nil))
+
+	s.RegisterMethod("stream", false, false, true, false)
+	require.NoError(t, server.RegisterStreamHandler[*internal.Request, *internal.Response](s, "stream", nil,
+		func(stream psrpc.ServerStream[*internal.Response, *internal.Request]) error {
+			metadata.SetHeader(stream.Context(), metadata.MD{"version": {"1.2.3"}, "set-cookie": {"a"}})
+			metadata.SetHeader(stream.Context(), metadata.MD{"set-cookie": {"b"}, "token-bin": {"\x00\xff"}})
+			for range stream.Channel() {
+			}
+			metadata.SetTrailer(stream.Context(), metadata.MD{"remaining": {"9"}})
+			return nil
+		},
+		nil,
+	))
+
+	c, err := client.NewRPCClientWithStreams(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, b)
+	require.NoError(t, err)
+	c.RegisterMethod("unary", false, false, false, false)
+	c.RegisterMethod("multi", false, true, false, false)
+	c.RegisterMethod("stream", false, false, true, false)
+
+	expected := metadata.ResponseMetadata{
+		Header:  metadata.MD{"version": {"1.2.3"}, "set-cookie": {"a", "b"}, "token-bin": {"\x00\xff"}},
+		Trailer: metadata.MD{"remaining": {"9"}},
+	}
+
+	t.Run("Unary", func(t *testing.T) {
+		var md metadata.ResponseMetadata
+		_, err := client.RequestSingle[*internal.Response](context.Background(), c, "unary", nil, &internal.Request{},
+			psrpc.WithResponseMetadata(&md))
+		require.NoError(t, err)
+		require.Equal(t, expected, md)
+	})
+
+	t.Run("Multi", func(t *testing.T) {
+		resChan, err := client.RequestMulti[*internal.Response](context.Background(), c, "multi", nil, &internal.Request{},
+			psrpc.WithExpectedResponses(1))
+		require.NoError(t, err)
+
+		res := <-resChan
+		require.NoError(t, res.Err)
+		require.Equal(t, expected, res.Metadata)
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		var md metadata.ResponseMetadata
+		stream, err := client.OpenStream[*internal.Request, *internal.Response](context.Background(), c, "stream", nil,
+			psrpc.WithResponseMetadata(&md))
+		require.NoError(t, err)
+
+		require.NoError(t, stream.CloseSend())
+		select {
+		case <-stream.Context().Done():
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for stream to close")
+		}
+		require.Equal(t, expected, md)
+	})
+}
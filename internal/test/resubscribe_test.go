@@ -0,0 +1,184 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/internal"
+	"github.com/livekit/psrpc/internal/bus"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+	"github.com/livekit/psrpc/testutils"
+)
+
+// flakyBus fails every open subscription when drop is called
+type flakyBus struct {
+	mu     sync.Mutex
+	broken chan struct{}
+}
+
+type readResult struct {
+	b  []byte
+	ok bool
+}
+
+func (f *flakyBus) drop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	close(f.broken)
+	f.broken = make(chan struct{})
+}
+
+func (f *flakyBus) interceptor(_ context.Context, _ testutils.Channel, next testutils.ReadHandler) testutils.ReadHandler {
+	f.mu.Lock()
+	broken := f.broken
+	f.mu.Unlock()
+
+	results := make(chan readResult)
+	go func() {
+		for {
+			b, ok := next()
+			select {
+			case results <- readResult{b, ok}:
+			case <-broken:
+				return
+			}
+			if !ok {
+				return
+			}
+		}
+	}()
+
+	return func() ([]byte, bool) {
+		select {
+		case r := <-results:
+			return r.b, r.ok
+		case <-broken:
+			return nil, false
+		}
+	}
+}
+
+type stateRecorder struct {
+	mu     sync.Mutex
+	states map[psrpc.SubscriptionState]int
+}
+
+func (r *stateRecorder) record(_ string, state psrpc.SubscriptionState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states[state]++
+}
+
+func (r *stateRecorder) count(state psrpc.SubscriptionState) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.states[state]
+}
+
+func TestResubscribe(t *testing.T) {
+	serviceName := "test_resubscribe"
+	flaky := &flakyBus{broken: make(chan struct{})}
+	b := testutils.NewTestBus(bus.NewLocalMessageBus(), testutils.WithSubscribeInterceptor(flaky.interceptor))
+
+	serverStates := &stateRecorder{states: map[psrpc.SubscriptionState]int{}}
+	s := server.NewRPCServer(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   "server",
+	}, b, psrpc.WithServerSubscriptionStateFunc(serverStates.record))
+	t.Cleanup(func() { s.Close(true) })
+
+	s.RegisterMethod("unary", false, false, false, false)
+	require.NoError(t, server.RegisterHandler[*internal.Request, *internal.Response](s, "unary", nil,
+		func(ctx context.Context, req *internal.Request) (*internal.Response, error) {
+			return &internal.Response{}, nil
+		},
+		nil,
+	))
+	s.RegisterMethod("updates", false, true, false, false)
+
+	clientStates := &stateRecorder{states: map[psrpc.SubscriptionState]int{}}
+	c, err := client.NewRPCClient(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, b, psrpc.WithClientSubscriptionStateFunc(clientStates.record))
+	require.NoError(t, err)
+	t.Cleanup(c.Close)
+	c.RegisterMethod("unary", false, false, false, false)
+	c.RegisterMethod("updates", false, true, false, false)
+
+	sub, err := client.Join[*internal.Request](context.Background(), c, "updates", nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sub.Close() })
+
+	flaky.drop()
+
+	// the server's request, direct and claim response subscriptions, and the client's response, claim and joined subscriptions
+	require.Eventually(t, func() bool {
+		return serverStates.count(psrpc.SubscriptionRestored) == 3 && clientStates.count(psrpc.SubscriptionRestored) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, 3, serverStates.count(psrpc.SubscriptionLost))
+	require.Equal(t, 3, clientStates.count(psrpc.SubscriptionLost))
+
+	_, err = client.RequestSingle[*internal.Response](context.Background(), c, "unary", nil, &internal.Request{})
+	require.NoError(t, err)
+
+	require.NoError(t, s.Publish(context.Background(), "updates", nil, &internal.Request{RequestId: "update"}))
+	select {
+	case msg := <-sub.Channel():
+		require.Equal(t, "update", msg.RequestId)
+	case <-time.After(time.Second):
+		t.Fatal("joined subscription was not restored")
+	}
+}
+
+func TestResubscribeContextDone(t *testing.T) {
+	serviceName := "test_resubscribe_context_done"
+	b := bus.NewLocalMessageBus()
+
+	states := &stateRecorder{states: map[psrpc.SubscriptionState]int{}}
+	c, err := client.NewRPCClient(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, b, psrpc.WithClientSubscriptionStateFunc(states.record))
+	require.NoError(t, err)
+	t.Cleanup(c.Close)
+	c.RegisterMethod("updates", false, true, false, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sub, err := client.Join[*internal.Request](ctx, c, "updates", nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sub.Close() })
+
+	cancel()
+
+	select {
+	case _, ok := <-sub.Channel():
+		require.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("joined subscription was not closed")
+	}
+	require.Zero(t, states.count(psrpc.SubscriptionLost))
+	require.Zero(t, states.count(psrpc.SubscriptionRestored))
+}
@@ -0,0 +1,272 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/internal"
+	"github.com/livekit/psrpc/internal/bus"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+)
+
+// executionCounter counts handler executions by request ID, and blocks the request with ID "block" until released
+type executionCounter struct {
+	mu      sync.Mutex
+	counts  map[string]int
+	release chan struct{}
+}
+
+func newExecutionCounter() *executionCounter {
+	return &executionCounter{
+		counts:  map[string]int{},
+		release: make(chan struct{}),
+	}
+}
+
+func (e *executionCounter) handler(id string) func(context.Context, *internal.Request) (*internal.Response, error) {
+	return func(ctx context.Context, req *internal.Request) (*internal.Response, error) {
+		e.mu.Lock()
+		e.counts[req.RequestId]++
+		e.mu.Unlock()
+		if req.RequestId == "block" {
+			<-e.release
+		}
+		return &internal.Response{RequestId: req.RequestId, ServerId: id}, nil
+	}
+}
+
+func (e *executionCounter) count(requestID string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.counts[requestID]
+}
+
+func TestStickyRoutingQueued(t *testing.T) {
+	serviceName := "test_sticky_queued"
+	rpc := "get_server"
+	b := bus.NewLocalMessageBus()
+
+	e := newExecutionCounter()
+	for _, id := range []string{"a", "b"} {
+		s := server.NewRPCServer(&info.ServiceDefinition{
+			Name: serviceName,
+			ID:   id,
+		}, b, psrpc.WithServerMaxConcurrency(1), psrpc.WithServerQueue(1, 5*time.Second))
+		t.Cleanup(func() { s.Close(true) })
+
+		s.RegisterMethod(rpc, true, false, true, false)
+		require.NoError(t, server.RegisterHandler[*internal.Request, *internal.Response](s, rpc, nil, e.handler(id), nil))
+	}
+
+	c, err := client.NewRPCClient(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, b, psrpc.WithClientStickyRouting(time.Minute), psrpc.WithClientSelectTimeout(100*time.Millisecond))
+	require.NoError(t, err)
+	t.Cleanup(c.Close)
+	c.RegisterMethod(rpc, true, false, true, false)
+
+	request := func(id string) (*internal.Response, error) {
+		return client.RequestSingle[*internal.Response](context.Background(), c, rpc, nil,
+			&internal.Request{RequestId: id}, psrpc.WithRoutingKey("session"))
+	}
+
+	res, err := request("first")
+	require.NoError(t, err)
+	selected := res.ServerId
+
+	go func() { _, _ = request("block") }()
+	require.Eventually(t, func() bool { return e.count("block") == 1 }, time.Second, 10*time.Millisecond)
+
+	// the request waits in the sticky server's queue for longer than the selection timeout
+	queued := make(chan *internal.Response, 1)
+	go func() {
+		res, err := request("queued")
+		require.NoError(t, err)
+		queued <- res
+	}()
+	time.Sleep(300 * time.Millisecond)
+	close(e.release)
+
+	select {
+	case res = <-queued:
+		require.Equal(t, selected, res.ServerId)
+	case <-time.After(time.Second):
+		t.Fatal("queued request did not complete")
+	}
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, 1, e.count("queued"))
+}
+
+func TestStickyRoutingFallback(t *testing.T) {
+	serviceName := "test_sticky_fallback"
+	rpc := "get_server"
+	b := bus.NewLocalMessageBus()
+
+	e := newExecutionCounter()
+	var slow atomic.String
+	for _, id := range []string{"a", "b"} {
+		id := id
+		s := server.NewRPCServer(&info.ServiceDefinition{
+			Name: serviceName,
+			ID:   id,
+		}, b, psrpc.WithServerMaxConcurrency(1), psrpc.WithServerQueue(1, 200*time.Millisecond))
+		t.Cleanup(func() { s.Close(true) })
+
+		s.RegisterMethod(rpc, true, false, true, false)
+		require.NoError(t, server.RegisterHandler[*internal.Request, *internal.Response](s, rpc, nil, e.handler(id),
+			func(ctx context.Context, req *internal.Request) float32 {
+				// the sticky server accepts the "late" request after the client's selection timeout
+				if req.RequestId == "late" && slow.Load() == id {
+					time.Sleep(300 * time.Millisecond)
+				}
+				return 1
+			},
+		))
+	}
+
+	c, err := client.NewRPCClient(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, b, psrpc.WithClientStickyRouting(time.Minute), psrpc.WithClientSelectTimeout(100*time.Millisecond))
+	require.NoError(t, err)
+	t.Cleanup(c.Close)
+	c.RegisterMethod(rpc, true, false, true, false)
+
+	request := func(id string) (*internal.Response, error) {
+		return client.RequestSingle[*internal.Response](context.Background(), c, rpc, nil,
+			&internal.Request{RequestId: id}, psrpc.WithRoutingKey("session"))
+	}
+
+	res, err := request("first")
+	require.NoError(t, err)
+	selected := res.ServerId
+
+	t.Run("late accept", func(t *testing.T) {
+		slow.Store(selected)
+		res, err := request("late")
+		require.NoError(t, err)
+		require.NotEqual(t, selected, res.ServerId)
+		selected = res.ServerId
+
+		time.Sleep(time.Second)
+		require.Equal(t, 1, e.count("late"))
+	})
+
+	t.Run("overloaded", func(t *testing.T) {
+		go func() { _, _ = request("block") }()
+		require.Eventually(t, func() bool { return e.count("block") == 1 }, time.Second, 10*time.Millisecond)
+		defer close(e.release)
+
+		res, err := request("overloaded")
+		require.NoError(t, err)
+		require.NotEqual(t, selected, res.ServerId)
+		require.Equal(t, 1, e.count("overloaded"))
+
+		res, err = request("moved")
+		require.NoError(t, err)
+		require.NotEqual(t, selected, res.ServerId)
+	})
+}
+
+func TestTargetServerSaturated(t *testing.T) {
+	serviceName := "test_target_saturated"
+	rpc := "get_server"
+	b := bus.NewLocalMessageBus()
+
+	e := newExecutionCounter()
+	for _, id := range []string{"a", "b"} {
+		s := server.NewRPCServer(&info.ServiceDefinition{
+			Name: serviceName,
+			ID:   id,
+		}, b, psrpc.WithServerMaxConcurrency(1), psrpc.WithServerQueue(1, 300*time.Millisecond))
+		t.Cleanup(func() { s.Close(true) })
+
+		s.RegisterMethod(rpc, false, false, true, false)
+		require.NoError(t, server.RegisterHandler[*internal.Request, *internal.Response](s, rpc, nil, e.handler(id), nil))
+	}
+
+	c, err := client.NewRPCClient(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, b, psrpc.WithClientSelectTimeout(100*time.Millisecond))
+	require.NoError(t, err)
+	t.Cleanup(c.Close)
+	c.RegisterMethod(rpc, false, false, true, false)
+
+	request := func(id, target string) (*internal.Response, error) {
+		return client.RequestSingle[*internal.Response](context.Background(), c, rpc, nil,
+			&internal.Request{RequestId: id}, psrpc.WithTargetServer(target))
+	}
+
+	go func() { _, _ = request("block", "a") }()
+	require.Eventually(t, func() bool { return e.count("block") == 1 }, time.Second, 10*time.Millisecond)
+
+	t.Run("queue timeout", func(t *testing.T) {
+		_, err := request("expired", "a")
+		var perr psrpc.Error
+		require.ErrorAs(t, err, &perr)
+		require.Equal(t, psrpc.ResourceExhausted, perr.Code())
+		require.Equal(t, 0, e.count("expired"))
+	})
+
+	t.Run("queued", func(t *testing.T) {
+		queued := make(chan error, 1)
+		go func() {
+			res, err := request("queued", "a")
+			if err == nil {
+				require.Equal(t, "a", res.ServerId)
+			}
+			queued <- err
+		}()
+		time.Sleep(200 * time.Millisecond)
+		e.release <- struct{}{}
+
+		require.NoError(t, <-queued)
+		time.Sleep(100 * time.Millisecond)
+		require.Equal(t, 1, e.count("queued"))
+	})
+
+	t.Run("down", func(t *testing.T) {
+		_, err := request("down", "c")
+		require.ErrorIs(t, err, psrpc.ErrServerNotFound)
+		require.Equal(t, 0, e.count("down"))
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		var perr psrpc.Error
+		_, err := client.RequestMulti[*internal.Response](context.Background(), c, rpc, nil,
+			&internal.Request{RequestId: "multi"}, psrpc.WithTargetServer("a"))
+		require.ErrorAs(t, err, &perr)
+		require.Equal(t, psrpc.InvalidArgument, perr.Code())
+
+		_, err = client.OpenStream[*internal.Request, *internal.Response](context.Background(), c, rpc, nil,
+			psrpc.WithTargetServer("a"))
+		require.ErrorAs(t, err, &perr)
+		require.Equal(t, psrpc.InvalidArgument, perr.Code())
+		require.Equal(t, 0, e.count("multi"))
+	})
+}
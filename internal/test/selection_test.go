@@ -0,0 +1,115 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/internal"
+	"github.com/livekit/psrpc/internal/bus"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+)
+
+func TestSelectionStrategies(t *testing.T) {
+	serviceName := "test_selection"
+	rpc := "get_server"
+	b := bus.NewLocalMessageBus()
+
+	affinities := map[string]float32{"a": 0, "b": 0, "c": 1}
+	for id, affinity := range affinities {
+		id, affinity := id, affinity
+		s := server.NewRPCServer(&info.ServiceDefinition{
+			Name: serviceName,
+			ID:   id,
+		}, b)
+		t.Cleanup(func() { s.Close(true) })
+
+		s.RegisterMethod(rpc, true, false, true, false)
+		err := server.RegisterHandler[*internal.Request, *internal.Response](s, rpc, nil,
+			func(ctx context.Context, req *internal.Request) (*internal.Response, error) {
+				return &internal.Response{ServerId: id}, nil
+			},
+			func(ctx context.Context, req *internal.Request) float32 {
+				return affinity
+			},
+		)
+		require.NoError(t, err)
+	}
+
+	c, err := client.NewRPCClient(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, b)
+	require.NoError(t, err)
+	c.RegisterMethod(rpc, true, false, true, false)
+
+	requestWithOpts := func(selectionOpts psrpc.SelectionOpts, opts ...psrpc.RequestOption) string {
+		selectionOpts.AffinityTimeout = 100 * time.Millisecond
+		opts = append(opts, psrpc.WithSelectionOpts(selectionOpts))
+		res, err := client.RequestSingle[*internal.Response](context.Background(), c, rpc, nil, &internal.Request{}, opts...)
+		require.NoError(t, err)
+		return res.ServerId
+	}
+
+	request := func(selectionFunc psrpc.SelectionFunc) string {
+		return requestWithOpts(psrpc.SelectionOpts{SelectionFunc: selectionFunc})
+	}
+
+	t.Run("WeightedRandom", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			require.Equal(t, "c", request(psrpc.WeightedRandomSelection()))
+		}
+	})
+
+	t.Run("PowerOfTwoChoices", func(t *testing.T) {
+		// c wins every comparison it is part of
+		counts := map[string]int{}
+		for i := 0; i < 20; i++ {
+			counts[request(psrpc.PowerOfTwoChoicesSelection())]++
+		}
+		require.Greater(t, counts["c"], counts["a"])
+		require.Greater(t, counts["c"], counts["b"])
+	})
+
+	t.Run("ConsistentHash", func(t *testing.T) {
+		selectionOpts := psrpc.SelectionOpts{KeyedSelectionFunc: psrpc.ConsistentHashSelection()}
+		servers := map[string]bool{}
+		for key := 0; key < 5; key++ {
+			routingKey := psrpc.WithRoutingKey(strconv.Itoa(key))
+			id := requestWithOpts(selectionOpts, routingKey)
+			for i := 0; i < 2; i++ {
+				require.Equal(t, id, requestWithOpts(selectionOpts, routingKey))
+			}
+			servers[id] = true
+		}
+		require.Greater(t, len(servers), 1)
+	})
+
+	t.Run("RoundRobin", func(t *testing.T) {
+		selectionFunc := psrpc.RoundRobinSelection()
+		for _, id := range []string{"a", "b", "c", "a"} {
+			require.Equal(t, id, request(selectionFunc))
+		}
+	})
+}
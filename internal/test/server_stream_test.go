@@ -0,0 +1,91 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/internal"
+	"github.com/livekit/psrpc/internal/bus"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+)
+
+func TestServerStream(t *testing.T) {
+	serviceName := "test_server_stream"
+	rpc := "watch"
+	b := bus.NewLocalMessageBus()
+
+	for _, id := range []string{"a", "b"} {
+		id := id
+		s := server.NewRPCServer(&info.ServiceDefinition{
+			Name: serviceName,
+			ID:   id,
+		}, b)
+		t.Cleanup(func() { s.Close(true) })
+
+		s.RegisterMethod(rpc, true, false, true, false)
+		err := server.RegisterServerStreamHandler[*internal.Request, *internal.Response](s, rpc, nil,
+			func(req *internal.Request, stream psrpc.SendStream[*internal.Response]) error {
+				for i := 0; i < 10; i++ {
+					if err := stream.Send(&internal.Response{RequestId: req.RequestId, ServerId: id}); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			func(ctx context.Context, req *internal.Request) float32 {
+				if req.ClientId == id {
+					return 1
+				}
+				return 0.5
+			},
+		)
+		require.NoError(t, err)
+	}
+
+	c, err := client.NewRPCClientWithStreams(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, b, psrpc.WithClientChannelSize(2))
+	require.NoError(t, err)
+	c.RegisterMethod(rpc, true, false, true, false)
+
+	for _, id := range []string{"a", "b"} {
+		stream, err := client.OpenServerStream[*internal.Response](
+			context.Background(), c, rpc, nil, &internal.Request{RequestId: "req", ClientId: id},
+			psrpc.WithSelectionOpts(psrpc.SelectionOpts{AffinityTimeout: 100 * time.Millisecond}),
+		)
+		require.NoError(t, err)
+
+		// a slow consumer delays the server's sends, rather than closing the stream
+		received := 0
+		for res := range stream.Channel() {
+			require.Equal(t, "req", res.RequestId)
+			require.Equal(t, id, res.ServerId)
+			received++
+			time.Sleep(10 * time.Millisecond)
+		}
+		require.Equal(t, 10, received)
+		require.EqualError(t, stream.Err(), psrpc.ErrStreamClosed.Error())
+	}
+}
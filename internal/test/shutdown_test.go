@@ -0,0 +1,142 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/internal"
+	"github.com/livekit/psrpc/internal/bus"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+)
+
+func TestClientShutdown(t *testing.T) {
+	serviceName := "test_client_shutdown"
+	b := bus.NewLocalMessageBus()
+
+	s := server.NewRPCServer(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   "server",
+	}, b)
+	t.Cleanup(func() { s.Close(true) })
+
+	// handlers block until the channel stored for their request ID is closed
+	var releases sync.Map
+	handler := func(ctx context.Context, req *internal.Request) (*internal.Response, error) {
+		if release, ok := releases.Load(req.RequestId); ok {
+			<-release.(chan struct{})
+		}
+		return &internal.Response{}, nil
+	}
+	s.RegisterMethod("unary", false, false, false, false)
+	require.NoError(t, server.RegisterHandler[*internal.Request, *internal.Response](s, "unary", nil, handler, nil))
+	s.RegisterMethod("multi", false, true, false, false)
+	require.NoError(t, server.RegisterHandler[*internal.Request, *internal.Response](s, "multi", nil, handler, nil))
+	s.RegisterMethod("stream", false, false, true, false)
+	require.NoError(t, server.RegisterStreamHandler[*internal.Request, *internal.Response](s, "stream", nil,
+		func(stream psrpc.ServerStream[*internal.Response, *internal.Request]) error {
+			<-stream.Context().Done()
+			return nil
+		},
+		nil,
+	))
+
+	newClient := func() *client.RPCClient {
+		c, err := client.NewRPCClientWithStreams(&info.ServiceDefinition{
+			Name: serviceName,
+			ID:   rand.NewString(),
+		}, b)
+		require.NoError(t, err)
+		c.RegisterMethod("unary", false, false, false, false)
+		c.RegisterMethod("multi", false, true, false, false)
+		c.RegisterMethod("stream", false, false, true, false)
+		return c
+	}
+
+	newRequest := func(release chan struct{}) *internal.Request {
+		id := rand.NewRequestID()
+		releases.Store(id, release)
+		return &internal.Request{RequestId: id}
+	}
+
+	request := func(c *client.RPCClient, release chan struct{}) chan error {
+		req := newRequest(release)
+		errChan := make(chan error, 1)
+		go func() {
+			_, err := client.RequestSingle[*internal.Response](context.Background(), c, "unary", nil,
+				req, psrpc.WithRequestTimeout(5*time.Second))
+			errChan <- err
+		}()
+		return errChan
+	}
+
+	t.Run("Drain", func(t *testing.T) {
+		c := newClient()
+		release := make(chan struct{})
+		errChan := request(c, release)
+		time.Sleep(20 * time.Millisecond)
+		time.AfterFunc(80*time.Millisecond, func() { close(release) })
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		require.NoError(t, c.Shutdown(ctx))
+		require.NoError(t, <-errChan)
+
+		_, err := client.RequestSingle[*internal.Response](context.Background(), c, "unary", nil, &internal.Request{})
+		require.ErrorIs(t, err, psrpc.ErrClientClosed)
+	})
+
+	t.Run("Deadline", func(t *testing.T) {
+		c := newClient()
+		release := make(chan struct{})
+		defer close(release)
+		errChan := request(c, release)
+
+		resChan, err := client.RequestMulti[*internal.Response](context.Background(), c, "multi", nil,
+			newRequest(release), psrpc.WithRequestTimeout(5*time.Second))
+		require.NoError(t, err)
+
+		stream, err := client.OpenStream[*internal.Request, *internal.Response](context.Background(), c, "stream", nil)
+		require.NoError(t, err)
+		time.Sleep(20 * time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, c.Shutdown(ctx), context.DeadlineExceeded)
+
+		require.ErrorIs(t, <-errChan, psrpc.ErrClientClosed)
+
+		res := <-resChan
+		require.ErrorIs(t, res.Err, psrpc.ErrClientClosed)
+		_, ok := <-resChan
+		require.False(t, ok)
+
+		select {
+		case <-stream.Context().Done():
+			require.ErrorIs(t, stream.Err(), psrpc.ErrClientClosed)
+		case <-time.After(time.Second):
+			t.Fatal("stream was not closed")
+		}
+	})
+}
@@ -0,0 +1,110 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/internal"
+	"github.com/livekit/psrpc/internal/bus"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+)
+
+func TestStreamCloseSend(t *testing.T) {
+	for _, window := range []int{0, 4} {
+		t.Run("window="+strconv.Itoa(window), func(t *testing.T) {
+			testStreamCloseSend(t, window)
+		})
+	}
+}
+
+func testStreamCloseSend(t *testing.T, window int) {
+	serviceName := "test_stream_close_send"
+	rpc := "upload"
+	b := bus.NewLocalMessageBus()
+
+	s := server.NewRPCServer(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, b, psrpc.WithServerStreamWindow(window))
+	t.Cleanup(func() { s.Close(true) })
+
+	s.RegisterMethod(rpc, false, false, true, false)
+	err := server.RegisterStreamHandler[*internal.Request, *internal.Response](s, rpc, nil,
+		func(stream psrpc.ServerStream[*internal.Response, *internal.Request]) error {
+			// summarize the upload once the client is done sending
+			var count int
+			for range stream.Channel() {
+				count++
+			}
+			if err := stream.Send(&internal.Response{Code: strconv.Itoa(count)}); err != nil {
+				return err
+			}
+			if err := stream.CloseSend(); err != nil {
+				return err
+			}
+			<-stream.Context().Done()
+			return nil
+		},
+		nil,
+	)
+	require.NoError(t, err)
+
+	c, err := client.NewRPCClientWithStreams(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, b)
+	require.NoError(t, err)
+	c.RegisterMethod(rpc, false, false, true, false)
+
+	stream, err := client.OpenStream[*internal.Request, *internal.Response](
+		context.Background(), c, rpc, nil, psrpc.WithStreamWindow(window),
+	)
+	require.NoError(t, err)
+
+	const count = 10
+	for i := 0; i < count; i++ {
+		require.NoError(t, stream.Send(&internal.Request{RequestId: rand.NewRequestID()}))
+	}
+	require.NoError(t, stream.CloseSend())
+	require.ErrorIs(t, stream.Send(&internal.Request{}), psrpc.ErrStreamSendClosed)
+
+	select {
+	case res := <-stream.Channel():
+		require.Equal(t, strconv.Itoa(count), res.Code)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for summary")
+	}
+
+	// the server's half-close closes the client's channel, while the stream itself stays open
+	select {
+	case _, ok := <-stream.Channel():
+		require.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for channel to close")
+	}
+	require.NoError(t, stream.Err())
+
+	require.NoError(t, stream.Close(nil))
+}
@@ -0,0 +1,136 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/internal"
+	"github.com/livekit/psrpc/internal/bus"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/middleware"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+	"github.com/livekit/psrpc/testutils"
+)
+
+type keepaliveObserver struct {
+	middleware.MetricsObserver
+	unresponsive atomic.Int32
+}
+
+func (o *keepaliveObserver) OnStreamOpen(middleware.MetricRole, psrpc.RPCInfo)             {}
+func (o *keepaliveObserver) OnStreamClose(middleware.MetricRole, psrpc.RPCInfo)            {}
+func (o *keepaliveObserver) OnStreamRecv(middleware.MetricRole, psrpc.RPCInfo, error, int) {}
+func (o *keepaliveObserver) OnStreamSend(middleware.MetricRole, psrpc.RPCInfo, time.Duration, error, int) {
+}
+
+func (o *keepaliveObserver) OnStreamPeerUnresponsive(middleware.MetricRole, psrpc.RPCInfo) {
+	o.unresponsive.Inc()
+}
+
+func TestStreamKeepalive(t *testing.T) {
+	serviceName := "test_stream_keepalive"
+	rpc := "watch"
+	keepalive := psrpc.KeepaliveOpts{Interval: 20 * time.Millisecond, Misses: 3}
+
+	b := bus.NewLocalMessageBus()
+	// a dead server neither reads from nor publishes to the bus
+	dead := atomic.NewBool(false)
+	serverBus := testutils.NewTestBus(b,
+		testutils.WithPublishInterceptor(func(next testutils.PublishHandler) testutils.PublishHandler {
+			return func(ctx context.Context, channel testutils.Channel, msg proto.Message) error {
+				if dead.Load() {
+					return nil
+				}
+				return next(ctx, channel, msg)
+			}
+		}),
+		testutils.WithSubscribeInterceptor(func(_ context.Context, _ testutils.Channel, next testutils.ReadHandler) testutils.ReadHandler {
+			return func() ([]byte, bool) {
+				for {
+					b, ok := next()
+					if ok && dead.Load() {
+						continue
+					}
+					return b, ok
+				}
+			}
+		}),
+	)
+
+	serverObserver := &keepaliveObserver{}
+	s := server.NewRPCServer(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, serverBus, psrpc.WithServerStreamKeepalive(keepalive), middleware.WithServerMetrics(serverObserver))
+	t.Cleanup(func() { s.Close(true) })
+
+	serverErr := make(chan error, 1)
+	s.RegisterMethod(rpc, false, false, true, false)
+	err := server.RegisterStreamHandler[*internal.Request, *internal.Response](s, rpc, nil,
+		func(stream psrpc.ServerStream[*internal.Response, *internal.Request]) error {
+			<-stream.Context().Done()
+			serverErr <- stream.Err()
+			return nil
+		},
+		nil,
+	)
+	require.NoError(t, err)
+
+	clientObserver := &keepaliveObserver{}
+	c, err := client.NewRPCClientWithStreams(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, b, middleware.WithClientMetrics(clientObserver))
+	require.NoError(t, err)
+	c.RegisterMethod(rpc, false, false, true, false)
+
+	stream, err := client.OpenStream[*internal.Request, *internal.Response](
+		context.Background(), c, rpc, nil, psrpc.WithStreamKeepalive(keepalive),
+	)
+	require.NoError(t, err)
+
+	// pings keep an idle stream open while both peers respond
+	time.Sleep(10 * keepalive.Interval)
+	require.NoError(t, stream.Err())
+
+	dead.Store(true)
+
+	select {
+	case <-stream.Context().Done():
+	case <-time.After(time.Second):
+		t.Fatal("client stream was not closed")
+	}
+	require.ErrorIs(t, stream.Err(), psrpc.ErrPeerUnresponsive)
+	require.ErrorIs(t, stream.Err(), psrpc.Unavailable)
+	require.EqualValues(t, 1, clientObserver.unresponsive.Load())
+
+	select {
+	case err := <-serverErr:
+		require.ErrorIs(t, err, psrpc.ErrPeerUnresponsive)
+	case <-time.After(time.Second):
+		t.Fatal("server stream was not closed")
+	}
+	require.EqualValues(t, 1, serverObserver.unresponsive.Load())
+}
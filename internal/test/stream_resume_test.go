@@ -0,0 +1,149 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/internal"
+	"github.com/livekit/psrpc/internal/bus"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+	"github.com/livekit/psrpc/testutils"
+)
+
+type sessionKey struct{}
+
+func TestStreamResume(t *testing.T) {
+	serviceName := "test_stream_resume"
+	rpc := "session"
+	b := bus.NewLocalMessageBus()
+
+	handler := func(stream psrpc.ServerStream[*internal.Response, *internal.Request]) error {
+		session, _ := stream.Context().Value(sessionKey{}).(string)
+		for req := range stream.Channel() {
+			if err := stream.Send(&internal.Response{RequestId: req.RequestId, Code: session}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// the first server stops responding while a message is in flight
+	dead := atomic.NewBool(false)
+	deadBus := testutils.NewTestBus(b,
+		testutils.WithPublishInterceptor(func(next testutils.PublishHandler) testutils.PublishHandler {
+			return func(ctx context.Context, channel testutils.Channel, msg proto.Message) error {
+				if dead.Load() {
+					return nil
+				}
+				return next(ctx, channel, msg)
+			}
+		}),
+		testutils.WithSubscribeInterceptor(func(_ context.Context, _ testutils.Channel, next testutils.ReadHandler) testutils.ReadHandler {
+			return func() ([]byte, bool) {
+				for {
+					b, ok := next()
+					if ok && dead.Load() {
+						continue
+					}
+					return b, ok
+				}
+			}
+		}),
+	)
+
+	s1 := server.NewRPCServer(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, deadBus)
+	s1.RegisterMethod(rpc, false, false, true, false)
+	require.NoError(t, server.RegisterStreamHandler[*internal.Request, *internal.Response](s1, rpc, nil, handler, nil))
+
+	c, err := client.NewRPCClientWithStreams(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, b)
+	require.NoError(t, err)
+	c.RegisterMethod(rpc, false, false, true, false)
+
+	stream, err := client.OpenStream[*internal.Request, *internal.Response](
+		context.Background(), c, rpc, nil, psrpc.WithResumableStream(), psrpc.WithRequestTimeout(200*time.Millisecond),
+	)
+	require.NoError(t, err)
+	token := stream.ResumeToken()
+	require.NotEmpty(t, token)
+
+	require.NoError(t, stream.Send(&internal.Request{RequestId: "1"}))
+	res := <-stream.Channel()
+	require.Equal(t, "1", res.RequestId)
+
+	dead.Store(true)
+	require.ErrorIs(t, stream.Send(&internal.Request{RequestId: "2"}), psrpc.ErrRequestTimedOut)
+	require.NoError(t, stream.Close(psrpc.ErrPeerUnresponsive))
+	s1.Close(true)
+
+	// a replacement server restores the session for the resume token
+	s2 := server.NewRPCServer(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, b, psrpc.WithServerStreamResumeHook(func(ctx context.Context, info psrpc.RPCInfo, token string) (context.Context, error) {
+		if token == "unknown" {
+			return nil, psrpc.NewErrorf(psrpc.NotFound, "session not found")
+		}
+		return context.WithValue(ctx, sessionKey{}, "restored "+token), nil
+	}))
+	t.Cleanup(func() { s2.Close(true) })
+	s2.RegisterMethod(rpc, false, false, true, false)
+	require.NoError(t, server.RegisterStreamHandler[*internal.Request, *internal.Response](s2, rpc, nil, handler, nil))
+
+	_, err = client.OpenStream[*internal.Request, *internal.Response](
+		context.Background(), c, rpc, nil, psrpc.WithResume("unknown"),
+	)
+	require.ErrorIs(t, err, psrpc.NotFound)
+
+	// the message which was not acknowledged is replayed on the resumed stream
+	resumed, err := client.OpenStream[*internal.Request, *internal.Response](
+		context.Background(), c, rpc, nil, psrpc.WithResume(token),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resumed.Close(nil) })
+	require.Equal(t, token, resumed.ResumeToken())
+
+	select {
+	case res := <-resumed.Channel():
+		require.Equal(t, "2", res.RequestId)
+		require.Equal(t, "restored "+token, res.Code)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for replayed message")
+	}
+
+	require.NoError(t, resumed.Send(&internal.Request{RequestId: "3"}))
+	select {
+	case res := <-resumed.Channel():
+		require.Equal(t, "3", res.RequestId)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for response")
+	}
+}
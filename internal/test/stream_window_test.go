@@ -0,0 +1,89 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/internal"
+	"github.com/livekit/psrpc/internal/bus"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+)
+
+func TestStreamWindow(t *testing.T) {
+	serviceName := "test_stream_window"
+	rpc := "echo"
+	b := bus.NewLocalMessageBus()
+
+	s := server.NewRPCServer(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, b, psrpc.WithServerStreamWindow(8))
+	t.Cleanup(func() { s.Close(true) })
+
+	s.RegisterMethod(rpc, false, false, true, false)
+	err := server.RegisterStreamHandler[*internal.Request, *internal.Response](s, rpc, nil,
+		func(stream psrpc.ServerStream[*internal.Response, *internal.Request]) error {
+			for req := range stream.Channel() {
+				time.Sleep(time.Millisecond)
+				if err := stream.Send(&internal.Response{RequestId: req.RequestId}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		nil,
+	)
+	require.NoError(t, err)
+
+	c, err := client.NewRPCClientWithStreams(&info.ServiceDefinition{
+		Name: serviceName,
+		ID:   rand.NewString(),
+	}, b, psrpc.WithClientChannelSize(1))
+	require.NoError(t, err)
+	c.RegisterMethod(rpc, false, false, true, false)
+
+	stream, err := client.OpenStream[*internal.Request, *internal.Response](
+		context.Background(), c, rpc, nil, psrpc.WithStreamWindow(8),
+	)
+	require.NoError(t, err)
+
+	const count = 50
+	go func() {
+		for i := 0; i < count; i++ {
+			if err := stream.Send(&internal.Request{RequestId: rand.NewRequestID()}); err != nil {
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < count; i++ {
+		select {
+		case res := <-stream.Channel():
+			require.NotNil(t, res)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("missing response %d", i)
+		}
+	}
+	require.NoError(t, stream.Close(nil))
+}
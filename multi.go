@@ -0,0 +1,184 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package psrpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// MultiRequest sends a multi-RPC. The helpers below cancel its context once they have enough responses.
+type MultiRequest[ResponseType proto.Message] func(ctx context.Context) (<-chan *Response[ResponseType], error)
+
+var ErrInsufficientResponses = NewErrorf(Unavailable, "not enough successful responses")
+
+// MultiError reports the servers which returned errors to a multi-RPC helper
+type MultiError struct {
+	Err    error   // ErrInsufficientResponses if the helper could not complete, otherwise nil
+	Errors []error // errors returned by individual servers
+}
+
+func (e *MultiError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+
+	msg := fmt.Sprintf("%d servers returned errors", len(e.Errors))
+	if len(msgs) != 0 {
+		msg += ": " + strings.Join(msgs, "; ")
+	}
+	if e.Err != nil {
+		msg = e.Err.Error() + " (" + msg + ")"
+	}
+	return msg
+}
+
+func (e *MultiError) Unwrap() []error {
+	if e.Err == nil {
+		return e.Errors
+	}
+	return append([]error{e.Err}, e.Errors...)
+}
+
+// CollectAll waits for every response. If any server returns an error, the results are returned along with a *MultiError.
+func CollectAll[ResponseType proto.Message](ctx context.Context, req MultiRequest[ResponseType]) ([]ResponseType, error) {
+	var results []ResponseType
+	errs, err := collect(ctx, req, func(res ResponseType) bool {
+		results = append(results, res)
+		return false
+	})
+	if err != nil {
+		return results, err
+	}
+	return results, newMultiError(nil, errs)
+}
+
+// FirstN returns the first n successful responses, canceling the rest of the request. Errors from servers which
+// failed before then are returned with the results in a *MultiError. If fewer than n servers succeed, the
+// *MultiError also wraps ErrInsufficientResponses.
+func FirstN[ResponseType proto.Message](ctx context.Context, req MultiRequest[ResponseType], n int) ([]ResponseType, error) {
+	var results []ResponseType
+	errs, err := collect(ctx, req, func(res ResponseType) bool {
+		results = append(results, res)
+		return len(results) >= n
+	})
+	if err != nil {
+		return results, err
+	}
+	if len(results) < n {
+		return results, newMultiError(ErrInsufficientResponses, errs)
+	}
+	return results, newMultiError(nil, errs)
+}
+
+// Quorum returns the first k responses which agree on a key, canceling the rest of the request. Errors from
+// servers which failed before then are returned with the results in a *MultiError. If no key reaches k
+// responses, it returns a *MultiError wrapping ErrInsufficientResponses.
+func Quorum[ResponseType proto.Message](
+	ctx context.Context,
+	req MultiRequest[ResponseType],
+	k int,
+	key func(ResponseType) string,
+) ([]ResponseType, error) {
+	var results []ResponseType
+	groups := make(map[string][]ResponseType)
+	errs, err := collect(ctx, req, func(res ResponseType) bool {
+		kv := key(res)
+		groups[kv] = append(groups[kv], res)
+		if len(groups[kv]) >= k {
+			results = groups[kv]
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if results == nil {
+		return nil, newMultiError(ErrInsufficientResponses, errs)
+	}
+	return results, newMultiError(nil, errs)
+}
+
+// Reduce folds every successful response into an accumulator. Server errors are reported with a *MultiError.
+func Reduce[ResponseType proto.Message, T any](
+	ctx context.Context,
+	req MultiRequest[ResponseType],
+	initial T,
+	fn func(T, ResponseType) T,
+) (T, error) {
+	acc := initial
+	errs, err := collect(ctx, req, func(res ResponseType) bool {
+		acc = fn(acc, res)
+		return false
+	})
+	if err != nil {
+		return acc, err
+	}
+	return acc, newMultiError(nil, errs)
+}
+
+func newMultiError(err error, errs []error) error {
+	if err == nil && len(errs) == 0 {
+		return nil
+	}
+	return &MultiError{Err: err, Errors: errs}
+}
+
+// collect passes successful responses to fn until it returns true or the request completes, and
+// returns the errors from any servers which failed
+func collect[ResponseType proto.Message](
+	ctx context.Context,
+	req MultiRequest[ResponseType],
+	fn func(ResponseType) bool,
+) ([]error, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	resChan, err := req(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for {
+		select {
+		case res, ok := <-resChan:
+			if !ok {
+				return errs, nil
+			}
+			if res.Err != nil {
+				errs = append(errs, res.Err)
+			} else if fn(res.Result) {
+				go drain(resChan)
+				return errs, nil
+			}
+
+		case <-ctx.Done():
+			go drain(resChan)
+			return errs, ctx.Err()
+		}
+	}
+}
+
+// drain discards responses received after the request is canceled, until the channel closes
+func drain[ResponseType proto.Message](resChan <-chan *Response[ResponseType]) {
+	for range resChan {
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package psrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/psrpc/internal"
+)
+
+func testMultiRequest(responses ...*Response[*internal.Response]) (MultiRequest[*internal.Response], <-chan struct{}) {
+	canceled := make(chan struct{})
+	return func(ctx context.Context) (<-chan *Response[*internal.Response], error) {
+		resChan := make(chan *Response[*internal.Response])
+		go func() {
+			defer close(resChan)
+			for _, res := range responses {
+				select {
+				case resChan <- res:
+				case <-ctx.Done():
+					close(canceled)
+					return
+				}
+			}
+		}()
+		return resChan, nil
+	}, canceled
+}
+
+func TestMultiHelpers(t *testing.T) {
+	ctx := context.Background()
+	serverErr := NewErrorf(Internal, "failed")
+	ok := func(id string) *Response[*internal.Response] {
+		return &Response[*internal.Response]{Result: &internal.Response{ServerId: id}}
+	}
+	failed := &Response[*internal.Response]{Err: serverErr}
+
+	t.Run("CollectAll", func(t *testing.T) {
+		req, _ := testMultiRequest(ok("a"), failed, ok("b"))
+		results, err := CollectAll(ctx, req)
+		require.Len(t, results, 2)
+
+		var multiErr *MultiError
+		require.True(t, errors.As(err, &multiErr))
+		require.Equal(t, []error{serverErr}, multiErr.Errors)
+		require.True(t, errors.Is(err, Internal))
+	})
+
+	t.Run("FirstN", func(t *testing.T) {
+		req, canceled := testMultiRequest(ok("a"), failed, ok("b"), ok("c"))
+		results, err := FirstN(ctx, req, 2)
+		require.Len(t, results, 2)
+		var multiErr *MultiError
+		require.True(t, errors.As(err, &multiErr))
+		require.NoError(t, multiErr.Err)
+		require.Equal(t, []error{serverErr}, multiErr.Errors)
+		<-canceled
+
+		req, _ = testMultiRequest(ok("a"), failed)
+		results, err = FirstN(ctx, req, 2)
+		require.Len(t, results, 1)
+		require.True(t, errors.Is(err, ErrInsufficientResponses))
+		require.True(t, errors.Is(err, Internal))
+	})
+
+	t.Run("Quorum", func(t *testing.T) {
+		key := func(res *internal.Response) string { return res.ServerId }
+
+		req, canceled := testMultiRequest(ok("a"), ok("b"), ok("a"), ok("b"))
+		results, err := Quorum(ctx, req, 2, key)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		require.Equal(t, "a", results[0].ServerId)
+		<-canceled
+
+		req, canceled = testMultiRequest(ok("a"), failed, ok("a"), ok("b"))
+		results, err = Quorum(ctx, req, 2, key)
+		require.Len(t, results, 2)
+		var multiErr *MultiError
+		require.True(t, errors.As(err, &multiErr))
+		require.NoError(t, multiErr.Err)
+		require.Equal(t, []error{serverErr}, multiErr.Errors)
+		<-canceled
+
+		req, _ = testMultiRequest(ok("a"), ok("b"))
+		_, err = Quorum(ctx, req, 2, key)
+		require.True(t, errors.Is(err, ErrInsufficientResponses))
+	})
+
+	t.Run("Reduce", func(t *testing.T) {
+		req, _ := testMultiRequest(ok("a"), ok("b"), ok("c"))
+		ids, err := Reduce(ctx, req, "", func(acc string, res *internal.Response) string {
+			return acc + res.ServerId
+		})
+		require.NoError(t, err)
+		require.Equal(t, "abc", ids)
+	})
+}
@@ -17,6 +17,7 @@ package client
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/frostbyte73/core"
 
@@ -24,19 +25,28 @@ import (
 	"github.com/livekit/psrpc/internal"
 	"github.com/livekit/psrpc/internal/bus"
 	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/metadata"
 )
 
 type RPCClient struct {
 	*info.ServiceDefinition
 	psrpc.ClientOpts
 
-	bus bus.MessageBus
+	bus    bus.MessageBus
+	sticky *stickyRoutes
 
 	mu               sync.RWMutex
 	claimRequests    map[string]chan *internal.ClaimRequest
 	responseChannels map[string]chan *internal.Response
 	streamChannels   map[string]chan *internal.Stream
+	resumableStreams map[string]resumableEntry
+	lastResumePrune  time.Time
 	closed           core.Fuse
+
+	// requests in flight, tracked so Shutdown can wait for them
+	active   int
+	draining bool
+	idle     chan struct{}
 }
 
 func NewRPCClientWithStreams(
@@ -59,21 +69,25 @@ func NewRPCClient(
 		claimRequests:     make(map[string]chan *internal.ClaimRequest),
 		responseChannels:  make(map[string]chan *internal.Response),
 		streamChannels:    make(map[string]chan *internal.Stream),
+		resumableStreams:  make(map[string]resumableEntry),
 	}
 	if c.ClientID != "" {
 		c.ID = c.ClientID
 	}
+	if c.StickyRoutingTTL > 0 {
+		c.sticky = newStickyRoutes(c.StickyRoutingTTL)
+	}
 
 	ctx := context.Background()
-	responses, err := bus.Subscribe[*internal.Response](
-		ctx, c.bus, info.GetResponseChannel(c.Name, c.ID), c.ChannelSize,
+	responses, err := bus.SubscribeResilient[*internal.Response](
+		ctx, c.bus, info.GetResponseChannel(c.Name, c.ID), c.ChannelSize, c.SubscriptionState,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	claims, err := bus.Subscribe[*internal.ClaimRequest](
-		ctx, c.bus, info.GetClaimRequestChannel(c.Name, c.ID), c.ChannelSize,
+	claims, err := bus.SubscribeResilient[*internal.ClaimRequest](
+		ctx, c.bus, info.GetClaimRequestChannel(c.Name, c.ID), c.ChannelSize, c.SubscriptionState,
 	)
 	if err != nil {
 		_ = responses.Close()
@@ -82,8 +96,8 @@ func NewRPCClient(
 
 	var streams bus.Subscription[*internal.Stream]
 	if c.EnableStreams {
-		streams, err = bus.Subscribe[*internal.Stream](
-			ctx, c.bus, info.GetStreamChannel(c.Name, c.ID), c.ChannelSize,
+		streams, err = bus.SubscribeResilient[*internal.Stream](
+			ctx, c.bus, info.GetStreamChannel(c.Name, c.ID), c.ChannelSize, c.SubscriptionState,
 		)
 		if err != nil {
 			_ = responses.Close()
@@ -94,6 +108,7 @@ func NewRPCClient(
 		streams = bus.EmptySubscription[*internal.Stream]{}
 	}
 
+	// the subscriptions resubscribe when the bus fails, so their channels only close after the client does
 	go func() {
 		closed := c.closed.Watch()
 		for {
@@ -104,10 +119,9 @@ func NewRPCClient(
 				_ = streams.Close()
 				return
 
-			case claim := <-claims.Channel():
-				if claim == nil {
-					c.Close()
-					continue
+			case claim, ok := <-claims.Channel():
+				if !ok {
+					return
 				}
 				c.mu.RLock()
 				claimChan, ok := c.claimRequests[claim.RequestId]
@@ -116,10 +130,9 @@ func NewRPCClient(
 					claimChan <- claim
 				}
 
-			case res := <-responses.Channel():
-				if res == nil {
-					c.Close()
-					continue
+			case res, ok := <-responses.Channel():
+				if !ok {
+					return
 				}
 				c.mu.RLock()
 				resChan, ok := c.responseChannels[res.RequestId]
@@ -128,10 +141,9 @@ func NewRPCClient(
 					resChan <- res
 				}
 
-			case msg := <-streams.Channel():
-				if msg == nil {
-					c.Close()
-					continue
+			case msg, ok := <-streams.Channel():
+				if !ok {
+					return
 				}
 				c.mu.RLock()
 				streamChan, ok := c.streamChannels[msg.StreamId]
@@ -146,6 +158,61 @@ func NewRPCClient(
 	return c, nil
 }
 
+// outgoingMetadata returns the encoded metadata for a request, and the route set on the context when the
+// request is propagated from a handler
+func outgoingMetadata(ctx context.Context) (map[string]string, map[string]*internal.MetadataValues, []string) {
+	strs, values := internal.EncodeMetadata(metadata.OutgoingContextMD(ctx))
+	return strs, values, metadata.OutgoingContextRoute(ctx)
+}
+
+// begin tracks a request until end is called, returning false if the client is closed or shutting down
+func (c *RPCClient) begin() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.draining || c.closed.IsBroken() {
+		return false
+	}
+	c.active++
+	return true
+}
+
+func (c *RPCClient) end() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.active--
+	if c.active == 0 && c.idle != nil {
+		close(c.idle)
+		c.idle = nil
+	}
+}
+
+// Shutdown stops new requests, and waits for requests and streams in flight to complete before closing the
+// client. If ctx is done first, the client is closed and the remaining requests fail with ErrClientClosed.
+func (c *RPCClient) Shutdown(ctx context.Context) error {
+	c.mu.Lock()
+	c.draining = true
+	idle := c.idle
+	if idle == nil && c.active > 0 {
+		idle = make(chan struct{})
+		c.idle = idle
+	}
+	c.mu.Unlock()
+
+	var err error
+	if idle != nil {
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+
+	c.Close()
+	return err
+}
+
 func (c *RPCClient) Close() {
 	c.closed.Break()
 }
@@ -88,7 +88,54 @@ func testAffinity(t *testing.T, opts psrpc.SelectionOpts, expectedID string) {
 			Affinity:  0.9,
 		}
 	}()
-	serverID, err := selectServer(context.Background(), c, nil, opts)
+	serverID, err := selectServer(context.Background(), c, nil, "", opts)
 	require.NoError(t, err)
 	require.Equal(t, expectedID, serverID)
 }
+
+type testResumableStream struct {
+	token   string
+	err     error
+	unacked [][]byte
+	replay  [][]byte
+}
+
+func (s *testResumableStream) ResumeToken() string { return s.token }
+func (s *testResumableStream) Err() error          { return s.err }
+func (s *testResumableStream) Unacked() [][]byte   { return s.unacked }
+func (s *testResumableStream) Replay(payloads [][]byte) error {
+	s.replay = payloads
+	return nil
+}
+
+func TestResumableStreams(t *testing.T) {
+	c := &RPCClient{
+		ClientOpts:       psrpc.ClientOpts{StreamResumeTTL: 50 * time.Millisecond},
+		resumableStreams: make(map[string]resumableEntry),
+	}
+
+	closed := &testResumableStream{token: "closed", unacked: [][]byte{{1}}}
+	require.NoError(t, c.resumeStream("closed", closed))
+	closed.err = psrpc.NewError(psrpc.Canceled, psrpc.ErrStreamClosed)
+	c.releaseStream(closed)
+	require.NotContains(t, c.resumableStreams, "closed")
+
+	lost := &testResumableStream{token: "lost", unacked: [][]byte{{1}}}
+	require.NoError(t, c.resumeStream("lost", lost))
+	lost.err = psrpc.ErrPeerUnresponsive
+	c.releaseStream(lost)
+	require.Contains(t, c.resumableStreams, "lost")
+
+	resumed := &testResumableStream{token: "lost"}
+	require.NoError(t, c.resumeStream("lost", resumed))
+	require.Equal(t, lost.unacked, resumed.replay)
+
+	expired := &testResumableStream{token: "expired", unacked: [][]byte{{1}}}
+	require.NoError(t, c.resumeStream("expired", expired))
+	expired.err = psrpc.ErrPeerUnresponsive
+	c.releaseStream(expired)
+
+	time.Sleep(100 * time.Millisecond)
+	c.releaseStream(resumed)
+	require.NotContains(t, c.resumableStreams, "expired")
+}
@@ -0,0 +1,269 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/internal/bus"
+	"github.com/livekit/psrpc/internal/interceptors"
+	"github.com/livekit/psrpc/internal/logger"
+	"github.com/livekit/psrpc/pkg/info"
+)
+
+// Consume joins a subscription, and calls handler with each message until ctx is done or the consumer is closed
+func Consume[MessageType proto.Message](
+	ctx context.Context,
+	c *RPCClient,
+	rpc string,
+	topic []string,
+	handler func(context.Context, MessageType) error,
+	opts ...psrpc.ConsumeOption,
+) (psrpc.Consumer, error) {
+	sub, err := Join[MessageType](ctx, c, rpc, topic)
+	if err != nil {
+		return nil, err
+	}
+	return newConsumer(ctx, c.GetInfo(rpc, topic), sub, handler, opts...), nil
+}
+
+// ConsumeQueue joins a queue subscription, and calls handler with each message until ctx is done or the
+// consumer is closed
+func ConsumeQueue[MessageType proto.Message](
+	ctx context.Context,
+	c *RPCClient,
+	rpc string,
+	topic []string,
+	handler func(context.Context, MessageType) error,
+	opts ...psrpc.ConsumeOption,
+) (psrpc.Consumer, error) {
+	sub, err := JoinQueue[MessageType](ctx, c, rpc, topic)
+	if err != nil {
+		return nil, err
+	}
+	return newConsumer(ctx, c.GetInfo(rpc, topic), sub, handler, opts...), nil
+}
+
+type consumer[MessageType proto.Message] struct {
+	psrpc.ConsumeOpts
+	sub     bus.Subscription[MessageType]
+	handler psrpc.SubscriptionHandler
+
+	// each running handler holds a slot
+	slots chan struct{}
+	wg    sync.WaitGroup
+
+	mu     sync.Mutex
+	queues map[string]*keyQueue
+
+	closeOnce sync.Once
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// keyQueue holds the messages waiting for the handler of a busy ordering key
+type keyQueue struct {
+	msgs  []proto.Message
+	space chan struct{} // signalled when a waiting message is taken
+}
+
+func newConsumer[MessageType proto.Message](
+	ctx context.Context,
+	i *info.RequestInfo,
+	sub bus.Subscription[MessageType],
+	handler func(context.Context, MessageType) error,
+	opts ...psrpc.ConsumeOption,
+) psrpc.Consumer {
+	o := psrpc.ConsumeOpts{Concurrency: 1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.KeyQueueSize <= 0 {
+		o.KeyQueueSize = psrpc.DefaultConsumeKeyQueueSize
+	}
+	if o.ErrorHandler == nil {
+		o.ErrorHandler = func(msg proto.Message, err error) {
+			logger.Error(err, "subscription handler failed", "service", i.Service, "method", i.Method)
+		}
+	}
+
+	c := &consumer[MessageType]{
+		ConsumeOpts: o,
+		sub:         sub,
+		handler: interceptors.ChainClientInterceptors[psrpc.SubscriptionHandler](
+			o.Interceptors, i, func(ctx context.Context, msg proto.Message) error {
+				return handler(ctx, msg.(MessageType))
+			},
+		),
+		slots:  make(chan struct{}, max(o.Concurrency, 1)),
+		queues: make(map[string]*keyQueue),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go c.run(ctx)
+	return c
+}
+
+func (c *consumer[MessageType]) run(ctx context.Context) {
+	defer close(c.done)
+	defer c.wg.Wait()
+	defer func() { _ = c.sub.Close() }()
+
+	msgs := c.sub.Channel()
+	for {
+		select {
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+			if !c.dispatch(ctx, msg) {
+				return
+			}
+		case <-ctx.Done():
+			return
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// dispatch waits for a free slot, then starts handling the message, or queues it behind a message with the
+// same ordering key
+func (c *consumer[MessageType]) dispatch(ctx context.Context, msg proto.Message) bool {
+	if c.OrderingKey == nil {
+		if !c.acquire(ctx) {
+			return false
+		}
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			c.handle(ctx, msg)
+		}()
+		return true
+	}
+
+	key := c.OrderingKey(msg)
+	for {
+		c.mu.Lock()
+		q, busy := c.queues[key]
+		if !busy {
+			c.queues[key] = &keyQueue{space: make(chan struct{}, 1)}
+			c.mu.Unlock()
+			break
+		}
+		if len(q.msgs) < c.KeyQueueSize {
+			q.msgs = append(q.msgs, msg)
+			c.mu.Unlock()
+			return true
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-q.space:
+		case <-ctx.Done():
+			return false
+		case <-c.stop:
+			return false
+		}
+	}
+
+	if !c.acquire(ctx) {
+		c.removeQueue(key)
+		return false
+	}
+	c.wg.Add(1)
+	go c.handleKey(ctx, key, msg)
+	return true
+}
+
+// handleKey handles the messages with the same ordering key in order, taking a slot for each one
+func (c *consumer[MessageType]) handleKey(ctx context.Context, key string, msg proto.Message) {
+	defer c.wg.Done()
+
+	for {
+		c.handle(ctx, msg)
+
+		c.mu.Lock()
+		q := c.queues[key]
+		if len(q.msgs) == 0 {
+			delete(c.queues, key)
+			c.mu.Unlock()
+			return
+		}
+		msg = q.msgs[0]
+		q.msgs = q.msgs[1:]
+		c.mu.Unlock()
+
+		select {
+		case q.space <- struct{}{}:
+		default:
+		}
+
+		if !c.acquire(ctx) {
+			c.removeQueue(key)
+			return
+		}
+	}
+}
+
+func (c *consumer[MessageType]) acquire(ctx context.Context) bool {
+	select {
+	case c.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-c.stop:
+		return false
+	}
+}
+
+func (c *consumer[MessageType]) removeQueue(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.queues, key)
+}
+
+func (c *consumer[MessageType]) handle(ctx context.Context, msg proto.Message) {
+	defer func() { <-c.slots }()
+
+	if err := c.call(ctx, msg); err != nil {
+		c.ErrorHandler(msg, err)
+	}
+}
+
+func (c *consumer[MessageType]) call(ctx context.Context, msg proto.Message) (err error) {
+	if c.RecoverPanics {
+		defer func() {
+			if r := recover(); r != nil {
+				err = psrpc.NewErrorf(psrpc.Internal, "caught subscription handler panic: %v", r)
+			}
+		}()
+	}
+	return c.handler(ctx, msg)
+}
+
+func (c *consumer[MessageType]) Close() error {
+	c.closeOnce.Do(func() { close(c.stop) })
+	<-c.done
+	return nil
+}
+
+func (c *consumer[MessageType]) Done() <-chan struct{} {
+	return c.done
+}
@@ -16,6 +16,8 @@ package client
 
 import (
 	"context"
+	"errors"
+	"sort"
 	"time"
 
 	"google.golang.org/protobuf/proto"
@@ -80,25 +82,38 @@ type multiRPC[ResponseType proto.Message] struct {
 	requestID string
 	handler   psrpc.ClientMultiRPCHandler
 	resChan   chan<- *psrpc.Response[ResponseType]
+
+	// metadata for the response being passed through the handler
+	md metadata.ResponseMetadata
 }
 
 func (m *multiRPC[ResponseType]) Send(ctx context.Context, req proto.Message, opts ...psrpc.RequestOption) error {
 	o := getRequestOpts(ctx, m.i, m.c.ClientOpts, opts...)
+	if o.TargetServer != "" {
+		return psrpc.NewErrorf(psrpc.InvalidArgument, "target server is not supported for multi requests")
+	}
 
 	b, err := bus.SerializePayload(req)
 	if err != nil {
 		return psrpc.NewError(psrpc.MalformedRequest, err)
 	}
 
+	if !m.c.begin() {
+		return psrpc.ErrClientClosed
+	}
+
 	now := time.Now()
+	md, values, route := outgoingMetadata(ctx)
 	ir := &internal.Request{
-		RequestId:  m.requestID,
-		ClientId:   m.c.ID,
-		SentAt:     now.UnixNano(),
-		Expiry:     now.Add(o.Timeout).UnixNano(),
-		Multi:      true,
-		RawRequest: b,
-		Metadata:   metadata.OutgoingContextMetadata(ctx),
+		RequestId:      m.requestID,
+		ClientId:       m.c.ID,
+		SentAt:         now.UnixNano(),
+		Expiry:         now.Add(o.Timeout).UnixNano(),
+		Multi:          true,
+		RawRequest:     b,
+		Metadata:       md,
+		MetadataValues: values,
+		Route:          route,
 	}
 
 	resChan := make(chan *internal.Response, m.c.ChannelSize)
@@ -122,7 +137,13 @@ func (m *multiRPC[ResponseType]) handleResponses(
 	resChan chan *internal.Response,
 	opts psrpc.RequestOpts,
 ) {
+	defer m.c.end()
+
 	timer := time.NewTimer(opts.Timeout)
+	defer timer.Stop()
+
+	closed := m.c.closed.Watch()
+	e := newExpectedResponses(opts)
 	for {
 		select {
 		case res := <-resChan:
@@ -142,23 +163,109 @@ func (m *multiRPC[ResponseType]) handleResponses(
 				hook(ctx, req, m.i.RPCInfo, v, err)
 			}
 
+			m.md = metadata.ResponseMetadata{
+				Header:  internal.DecodeMetadata(res.Header, res.HeaderValues),
+				Trailer: internal.DecodeMetadata(res.Trailer, res.TrailerValues),
+			}
 			m.handler.Recv(v, err)
 
+			if e.add(res.ServerId) {
+				m.handler.Close()
+				return
+			}
+
 		case <-timer.C:
+			m.recvMissing(e, psrpc.DeadlineExceeded)
 			m.handler.Close()
 			return
 
 		case <-ctx.Done():
+			code := psrpc.DeadlineExceeded
+			if errors.Is(ctx.Err(), context.Canceled) {
+				code = psrpc.Canceled
+			}
+			m.recvMissing(e, code)
+			m.handler.Close()
+			return
+
+		case <-closed:
+			var v ResponseType
+			m.md = metadata.ResponseMetadata{}
+			m.handler.Recv(v, psrpc.ErrClientClosed)
 			m.handler.Close()
 			return
 		}
 	}
 }
 
+// recvMissing passes the expected responses which were not received to the handler as an error
+func (m *multiRPC[ResponseType]) recvMissing(e *expectedResponses, code psrpc.ErrorCode) {
+	if err := e.missing(); err != nil {
+		var v ResponseType
+		m.md = metadata.ResponseMetadata{}
+		m.handler.Recv(v, psrpc.NewError(code, err))
+	}
+}
+
+// expectedResponses tracks the responses to a multi-RPC against the count and servers the caller expects
+type expectedResponses struct {
+	count   int
+	servers map[string]bool
+	pending int
+}
+
+func newExpectedResponses(opts psrpc.RequestOpts) *expectedResponses {
+	e := &expectedResponses{count: opts.ExpectedResponses}
+	if len(opts.ExpectedServers) != 0 {
+		e.servers = make(map[string]bool, len(opts.ExpectedServers))
+		for _, id := range opts.ExpectedServers {
+			e.servers[id] = false
+		}
+		e.pending = len(e.servers)
+	}
+	return e
+}
+
+func (e *expectedResponses) enabled() bool {
+	return e.count > 0 || e.servers != nil
+}
+
+// add records a response, returning true once every expected response has been received
+func (e *expectedResponses) add(serverID string) bool {
+	if !e.enabled() {
+		return false
+	}
+
+	e.count--
+	if responded, ok := e.servers[serverID]; ok && !responded {
+		e.servers[serverID] = true
+		e.pending--
+	}
+	return e.count <= 0 && e.pending == 0
+}
+
+func (e *expectedResponses) missing() *psrpc.MissingResponsesError {
+	if !e.enabled() {
+		return nil
+	}
+
+	err := &psrpc.MissingResponsesError{}
+	for id, responded := range e.servers {
+		if !responded {
+			err.ServerIDs = append(err.ServerIDs, id)
+		}
+	}
+	sort.Strings(err.ServerIDs)
+
+	err.Count = max(e.count, len(err.ServerIDs))
+	return err
+}
+
 func (m *multiRPC[ResponseType]) Recv(msg proto.Message, err error) {
 	m.resChan <- &psrpc.Response[ResponseType]{
-		Result: msg.(ResponseType),
-		Err:    err,
+		Result:   msg.(ResponseType),
+		Err:      err,
+		Metadata: m.md,
 	}
 }
 
@@ -34,6 +34,7 @@ func getClientOpts(opts ...psrpc.ClientOption) psrpc.ClientOpts {
 	o := &psrpc.ClientOpts{
 		SelectionTimeout: psrpc.DefaultAffinityTimeout,
 		ChannelSize:      bus.DefaultChannelSize,
+		StreamResumeTTL:  psrpc.DefaultStreamResumeTTL,
 	}
 	for _, opt := range opts {
 		opt(o)
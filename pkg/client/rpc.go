@@ -26,7 +26,6 @@ import (
 	"github.com/livekit/psrpc/internal/bus"
 	"github.com/livekit/psrpc/internal/interceptors"
 	"github.com/livekit/psrpc/pkg/info"
-	"github.com/livekit/psrpc/pkg/metadata"
 	"github.com/livekit/psrpc/pkg/rand"
 )
 
@@ -38,10 +37,11 @@ func RequestSingle[ResponseType proto.Message](
 	request proto.Message,
 	opts ...psrpc.RequestOption,
 ) (response ResponseType, err error) {
-	if c.closed.IsBroken() {
+	if !c.begin() {
 		err = psrpc.ErrClientClosed
 		return
 	}
+	defer c.end()
 
 	i := c.GetInfo(rpc, topic)
 
@@ -73,6 +73,21 @@ func RequestSingle[ResponseType proto.Message](
 	return
 }
 
+// RequestSingleAsync sends a request without waiting for the response. The request runs through the same
+// interceptors and hooks as RequestSingle.
+func RequestSingleAsync[ResponseType proto.Message](
+	ctx context.Context,
+	c *RPCClient,
+	rpc string,
+	topic []string,
+	request proto.Message,
+	opts ...psrpc.RequestOption,
+) *psrpc.Future[ResponseType] {
+	return psrpc.NewFuture(func() (ResponseType, error) {
+		return RequestSingle[ResponseType](ctx, c, rpc, topic, request, opts...)
+	})
+}
+
 func newRPC[ResponseType proto.Message](c *RPCClient, i *info.RequestInfo) psrpc.ClientRPCHandler {
 	return func(ctx context.Context, request proto.Message, opts ...psrpc.RequestOption) (response proto.Message, err error) {
 		o := getRequestOpts(ctx, i, c.ClientOpts, opts...)
@@ -83,63 +98,158 @@ func newRPC[ResponseType proto.Message](c *RPCClient, i *info.RequestInfo) psrpc
 			return
 		}
 
-		requestID := rand.NewRequestID()
-		now := time.Now()
-		req := &internal.Request{
-			RequestId:  requestID,
-			ClientId:   c.ID,
-			SentAt:     now.UnixNano(),
-			Expiry:     now.Add(o.Timeout).UnixNano(),
-			Multi:      false,
-			RawRequest: b,
-			Metadata:   metadata.OutgoingContextMetadata(ctx),
+		ctx, cancel := context.WithTimeout(ctx, o.Timeout)
+		defer cancel()
+
+		if o.TargetServer != "" {
+			response, _, err = sendRequest[ResponseType](ctx, c, i, o, b, o.TargetServer)
+			return
 		}
 
-		var claimChan chan *internal.ClaimRequest
-		resChan := make(chan *internal.Response, 1)
+		var stickyKey string
+		if c.sticky != nil && o.RoutingKey != "" && i.RequireClaim {
+			stickyKey = i.GetHandlerKey() + "|" + o.RoutingKey
+			if serverID, ok := c.sticky.get(stickyKey); ok {
+				response, _, err = sendRequest[ResponseType](ctx, c, i, o, b, serverID)
+				if !canReroute(err) {
+					return
+				}
+				c.sticky.remove(stickyKey, serverID)
+			}
+		}
 
-		c.mu.Lock()
-		if i.RequireClaim {
-			claimChan = make(chan *internal.ClaimRequest, c.ChannelSize)
-			c.claimRequests[requestID] = claimChan
+		response, serverID, err := sendRequest[ResponseType](ctx, c, i, o, b, "")
+		if err == nil && stickyKey != "" {
+			c.sticky.set(stickyKey, serverID)
 		}
-		c.responseChannels[requestID] = resChan
+		return
+	}
+}
+
+// canReroute reports whether a request sent to a sticky server can be sent to another server instead,
+// because the server could not be reached, rejected the request or was too busy to handle it.
+func canReroute(err error) bool {
+	if errors.Is(err, psrpc.ErrServerNotFound) || errors.Is(err, psrpc.ErrRequestRejected) {
+		return true
+	}
+	var perr psrpc.Error
+	return errors.As(err, &perr) && perr.Code() == psrpc.ResourceExhausted
+}
+
+// sendRequest publishes a request and waits for the response, returning the ID of the server which handled it.
+// requests with a serverID are sent directly to that server, skipping server selection.
+func sendRequest[ResponseType proto.Message](
+	ctx context.Context,
+	c *RPCClient,
+	i *info.RequestInfo,
+	o psrpc.RequestOpts,
+	b []byte,
+	serverID string,
+) (response proto.Message, _ string, err error) {
+	direct := serverID != ""
+	requestID := rand.NewRequestID()
+	deadline, _ := ctx.Deadline()
+	md, values, route := outgoingMetadata(ctx)
+	now := time.Now()
+	req := &internal.Request{
+		RequestId:      requestID,
+		ClientId:       c.ID,
+		SentAt:         now.UnixNano(),
+		Expiry:         deadline.UnixNano(),
+		Multi:          false,
+		RawRequest:     b,
+		Metadata:       md,
+		MetadataValues: values,
+		Route:          route,
+		IdempotencyKey: o.IdempotencyKey,
+	}
+	// servers stop waiting to be selected once the client has moved on
+	if (i.RequireClaim || direct) && o.SelectionOpts.AffinityTimeout > 0 {
+		req.ClaimExpiry = now.Add(o.SelectionOpts.AffinityTimeout).UnixNano()
+	}
+
+	var claimChan chan *internal.ClaimRequest
+	resChan := make(chan *internal.Response, 1)
+
+	c.mu.Lock()
+	if i.RequireClaim || direct {
+		claimChan = make(chan *internal.ClaimRequest, c.ChannelSize)
+		c.claimRequests[requestID] = claimChan
+	}
+	c.responseChannels[requestID] = resChan
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.claimRequests, requestID)
+		delete(c.responseChannels, requestID)
 		c.mu.Unlock()
+	}()
 
-		defer func() {
-			c.mu.Lock()
-			if i.RequireClaim {
-				delete(c.claimRequests, requestID)
-			}
-			delete(c.responseChannels, requestID)
-			c.mu.Unlock()
-		}()
+	channel := i.GetRPCChannel()
+	if direct {
+		channel = i.GetServerRPCChannel(serverID)
+	}
+	if err = c.bus.Publish(ctx, channel, req); err != nil {
+		err = psrpc.NewError(psrpc.Internal, err)
+		return nil, "", err
+	}
 
-		if err = c.bus.Publish(ctx, i.GetRPCChannel(), req); err != nil {
-			err = psrpc.NewError(psrpc.Internal, err)
-			return
+	// direct requests are acknowledged with a claim, which must arrive within the selection timeout.
+	// the server waits for the accept to be confirmed before running the handler.
+	var acceptChan chan *internal.ClaimRequest
+	var acceptTimeout <-chan time.Time
+	if direct {
+		acceptChan = claimChan
+		if o.SelectionOpts.AffinityTimeout > 0 {
+			timer := time.NewTimer(o.SelectionOpts.AffinityTimeout)
+			defer timer.Stop()
+			acceptTimeout = timer.C
+		}
+	} else if i.RequireClaim {
+		serverID, err = selectServer(ctx, claimChan, resChan, o.RoutingKey, o.SelectionOpts)
+		if err != nil {
+			return nil, "", err
 		}
 
-		ctx, cancel := context.WithTimeout(ctx, o.Timeout)
-		defer cancel()
+		if err = c.bus.Publish(ctx, i.GetClaimResponseChannel(), &internal.ClaimResponse{
+			RequestId: requestID,
+			ServerId:  serverID,
+		}); err != nil {
+			err = psrpc.NewError(psrpc.Internal, err)
+			return nil, "", err
+		}
+	}
 
-		if i.RequireClaim {
-			serverID, err := selectServer(ctx, claimChan, resChan, o.SelectionOpts)
-			if err != nil {
-				return nil, err
+	closed := c.closed.Watch()
+	for {
+		select {
+		case claim := <-acceptChan:
+			if claim.Affinity < 0 {
+				return nil, "", psrpc.ErrRequestRejected
 			}
-
 			if err = c.bus.Publish(ctx, i.GetClaimResponseChannel(), &internal.ClaimResponse{
 				RequestId: requestID,
 				ServerId:  serverID,
 			}); err != nil {
 				err = psrpc.NewError(psrpc.Internal, err)
-				return nil, err
+				return nil, "", err
 			}
-		}
+			acceptChan = nil
+			acceptTimeout = nil
+
+		case <-acceptTimeout:
+			// withdraw the request so a late accept doesn't run the handler
+			_ = c.bus.Publish(ctx, i.GetClaimResponseChannel(), &internal.ClaimResponse{
+				RequestId: requestID,
+			})
+			return nil, "", psrpc.ErrServerNotFound
 
-		select {
 		case res := <-resChan:
+			if o.ResponseMetadata != nil {
+				o.ResponseMetadata.Header = internal.DecodeMetadata(res.Header, res.HeaderValues)
+				o.ResponseMetadata.Trailer = internal.DecodeMetadata(res.Trailer, res.TrailerValues)
+			}
 			if res.Error != "" {
 				err = psrpc.NewErrorFromResponse(res.Code, res.Error, res.ErrorDetails...)
 			} else {
@@ -148,6 +258,7 @@ func newRPC[ResponseType proto.Message](c *RPCClient, i *info.RequestInfo) psrpc
 					err = psrpc.NewError(psrpc.MalformedResponse, err)
 				}
 			}
+			return response, res.ServerId, err
 
 		case <-ctx.Done():
 			err = ctx.Err()
@@ -156,9 +267,11 @@ func newRPC[ResponseType proto.Message](c *RPCClient, i *info.RequestInfo) psrpc
 			} else if errors.Is(err, context.DeadlineExceeded) {
 				err = psrpc.ErrRequestTimedOut
 			}
-		}
+			return nil, "", err
 
-		return
+		case <-closed:
+			return nil, "", psrpc.ErrClientClosed
+		}
 	}
 }
 
@@ -166,6 +279,7 @@ func selectServer(
 	ctx context.Context,
 	claimChan chan *internal.ClaimRequest,
 	resChan chan *internal.Response,
+	routingKey string,
 	opts psrpc.SelectionOpts,
 ) (string, error) {
 
@@ -191,6 +305,8 @@ func selectServer(
 			switch {
 			case opts.SelectionFunc != nil:
 				return opts.SelectionFunc(claims)
+			case opts.KeyedSelectionFunc != nil:
+				return opts.KeyedSelectionFunc(routingKey, claims)
 			case serverID != "":
 				return serverID, nil
 			case resErr != nil:
@@ -208,8 +324,12 @@ func selectServer(
 					return claim.ServerId, nil
 				}
 
-				if opts.SelectionFunc != nil {
-					claims = append(claims, &psrpc.Claim{ServerID: claim.ServerId, Affinity: claim.Affinity})
+				if opts.SelectionFunc != nil || opts.KeyedSelectionFunc != nil {
+					claims = append(claims, &psrpc.Claim{
+						ServerID: claim.ServerId,
+						Affinity: claim.Affinity,
+						Metadata: claim.Metadata,
+					})
 				} else if claim.Affinity > affinity {
 					serverID = claim.ServerId
 					affinity = claim.Affinity
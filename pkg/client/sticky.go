@@ -0,0 +1,91 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+type stickyRoute struct {
+	serverID string
+	expiry   time.Time
+}
+
+type stickyRoutes struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	routes    map[string]stickyRoute
+	lastPrune time.Time
+}
+
+func newStickyRoutes(ttl time.Duration) *stickyRoutes {
+	return &stickyRoutes{
+		ttl:       ttl,
+		routes:    make(map[string]stickyRoute),
+		lastPrune: time.Now(),
+	}
+}
+
+func (r *stickyRoutes) get(key string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	route, ok := r.routes[key]
+	if !ok {
+		return "", false
+	}
+
+	now := time.Now()
+	if now.After(route.expiry) {
+		delete(r.routes, key)
+		return "", false
+	}
+
+	route.expiry = now.Add(r.ttl)
+	r.routes[key] = route
+	return route.serverID, true
+}
+
+func (r *stickyRoutes) set(key, serverID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.routes[key] = stickyRoute{
+		serverID: serverID,
+		expiry:   now.Add(r.ttl),
+	}
+
+	if now.Sub(r.lastPrune) > r.ttl {
+		r.lastPrune = now
+		for k, route := range r.routes {
+			if now.After(route.expiry) {
+				delete(r.routes, k)
+			}
+		}
+	}
+}
+
+// remove forgets a route, unless it has already been replaced by another server
+func (r *stickyRoutes) remove(key, serverID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if route, ok := r.routes[key]; ok && route.serverID == serverID {
+		delete(r.routes, key)
+	}
+}
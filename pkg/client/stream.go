@@ -23,6 +23,7 @@ import (
 
 	"github.com/livekit/psrpc"
 	"github.com/livekit/psrpc/internal"
+	"github.com/livekit/psrpc/internal/bus"
 	"github.com/livekit/psrpc/internal/logger"
 	"github.com/livekit/psrpc/internal/stream"
 	"github.com/livekit/psrpc/pkg/info"
@@ -37,13 +38,66 @@ func OpenStream[SendType, RecvType proto.Message](
 	topic []string,
 	opts ...psrpc.RequestOption,
 ) (psrpc.ClientStream[SendType, RecvType], error) {
+	return openStream[SendType, RecvType](ctx, c, rpc, topic, nil, nil, opts...)
+}
+
+// OpenServerStream sends a request to a server streaming RPC, and returns the stream of responses.
+// The server's sends block until the client has room to buffer them.
+func OpenServerStream[RecvType proto.Message](
+	ctx context.Context,
+	c *RPCClient,
+	rpc string,
+	topic []string,
+	request proto.Message,
+	opts ...psrpc.RequestOption,
+) (psrpc.ReceiveStream[RecvType], error) {
+	b, err := bus.SerializePayload(request)
+	if err != nil {
+		return nil, psrpc.NewError(psrpc.MalformedRequest, err)
+	}
+
+	return openStream[proto.Message, RecvType](ctx, c, rpc, topic, b, []stream.Option{stream.WithBlockingRecv()}, opts...)
+}
+
+func openStream[SendType, RecvType proto.Message](
+	ctx context.Context,
+	c *RPCClient,
+	rpc string,
+	topic []string,
+	rawRequest []byte,
+	streamOpts []stream.Option,
+	opts ...psrpc.RequestOption,
+) (stream.Stream[SendType, RecvType], error) {
 
 	i := c.GetInfo(rpc, topic)
 	o := getRequestOpts(ctx, i, c.ClientOpts, opts...)
+	if o.TargetServer != "" {
+		return nil, psrpc.NewErrorf(psrpc.InvalidArgument, "target server is not supported for streams")
+	}
+
+	if !c.begin() {
+		return nil, psrpc.ErrClientClosed
+	}
+
+	var resumeToken string
+	if o.StreamResumable {
+		resumeToken = o.ResumeToken
+		if resumeToken == "" {
+			resumeToken = rand.NewString()
+		}
+		streamOpts = append(streamOpts, stream.WithResumeToken(resumeToken), stream.WithReplay())
+	}
+	if o.ResponseMetadata != nil {
+		streamOpts = append(streamOpts, stream.WithResponseMetadata(o.ResponseMetadata))
+	}
 
 	streamID := rand.NewStreamID()
 	requestID := rand.NewRequestID()
 	now := time.Now()
+	if c.MetadataPropagation != nil {
+		ctx = metadata.NewContextWithPropagatedMetadata(ctx, c.MetadataPropagation)
+	}
+	md, values, route := outgoingMetadata(ctx)
 	req := &internal.Stream{
 		StreamId:  streamID,
 		RequestId: requestID,
@@ -51,8 +105,14 @@ func OpenStream[SendType, RecvType proto.Message](
 		Expiry:    now.Add(o.Timeout).UnixNano(),
 		Body: &internal.Stream_Open{
 			Open: &internal.StreamOpen{
-				NodeId:   c.ID,
-				Metadata: metadata.OutgoingContextMetadata(ctx),
+				NodeId:         c.ID,
+				RawRequest:     rawRequest,
+				Window:         uint32(o.StreamWindow),
+				ResumeToken:    resumeToken,
+				Resumed:        o.ResumeToken != "",
+				Route:          route,
+				Metadata:       md,
+				MetadataValues: values,
 			},
 		},
 	}
@@ -81,6 +141,7 @@ func OpenStream[SendType, RecvType proto.Message](
 		getRequestInterceptors(c.StreamInterceptors, o.Interceptors),
 		make(chan RecvType, c.ChannelSize),
 		map[string]chan struct{}{requestID: ackChan},
+		append(streamOpts, stream.WithRecvWindow(o.StreamWindow), stream.WithKeepalive(o.StreamKeepalive))...,
 	)
 
 	go runClientStream(c, cs, recvChan)
@@ -94,7 +155,7 @@ func OpenStream[SendType, RecvType proto.Message](
 	}
 
 	if i.RequireClaim {
-		serverID, err := selectServer(ctx, claimChan, nil, o.SelectionOpts)
+		serverID, err := selectServer(ctx, claimChan, nil, o.RoutingKey, o.SelectionOpts)
 		if err != nil {
 			_ = cs.Close(err)
 			return nil, err
@@ -111,18 +172,31 @@ func OpenStream[SendType, RecvType proto.Message](
 
 	select {
 	case <-ackChan:
+		if resumeToken != "" {
+			if err := c.resumeStream(resumeToken, cs); err != nil {
+				_ = cs.Close(err)
+				return nil, err
+			}
+		}
 		return cs, nil
 
+	case <-cs.Context().Done():
 	case <-ctx.Done():
-		err := ctx.Err()
-		if errors.Is(err, context.Canceled) {
-			err = psrpc.ErrRequestCanceled
-		} else if errors.Is(err, context.DeadlineExceeded) {
-			err = psrpc.ErrRequestTimedOut
-		}
-		_ = cs.Close(err)
-		return nil, err
 	}
+
+	// the server rejected the stream
+	if ctx.Err() == nil {
+		return nil, cs.Err()
+	}
+
+	err := ctx.Err()
+	if errors.Is(err, context.Canceled) {
+		err = psrpc.ErrRequestCanceled
+	} else if errors.Is(err, context.DeadlineExceeded) {
+		err = psrpc.ErrRequestTimedOut
+	}
+	_ = cs.Close(err)
+	return nil, err
 }
 
 func runClientStream[SendType, RecvType proto.Message](
@@ -130,6 +204,8 @@ func runClientStream[SendType, RecvType proto.Message](
 	stream stream.Stream[SendType, RecvType],
 	recvChan chan *internal.Stream,
 ) {
+	defer c.end()
+
 	ctx := stream.Context()
 	closed := c.closed.Watch()
 
@@ -137,10 +213,12 @@ func runClientStream[SendType, RecvType proto.Message](
 		select {
 		case <-ctx.Done():
 			_ = stream.Close(ctx.Err())
+			c.releaseStream(stream)
 			return
 
 		case <-closed:
-			_ = stream.Close(nil)
+			_ = stream.Close(psrpc.ErrClientClosed)
+			c.releaseStream(stream)
 			return
 
 		case is := <-recvChan:
@@ -153,6 +231,65 @@ func runClientStream[SendType, RecvType proto.Message](
 	}
 }
 
+type resumableStream interface {
+	ResumeToken() string
+	Err() error
+	Unacked() [][]byte
+	Replay(payloads [][]byte) error
+}
+
+// resumableEntry is a stream which can be resumed with its token. streams lost with unacknowledged messages
+// expire once they have not been resumed for the resume TTL.
+type resumableEntry struct {
+	stream resumableStream
+	expiry time.Time
+}
+
+// resumeStream replays messages which were not acknowledged before the stream with the same resume token was
+// lost, and tracks the new stream in its place
+func (c *RPCClient) resumeStream(token string, s resumableStream) error {
+	c.mu.Lock()
+	prev, ok := c.resumableStreams[token]
+	c.resumableStreams[token] = resumableEntry{stream: s}
+	c.mu.Unlock()
+
+	if !ok || (!prev.expiry.IsZero() && time.Now().After(prev.expiry)) {
+		return nil
+	}
+	return s.Replay(prev.stream.Unacked())
+}
+
+// releaseStream stops tracking a resumable stream once it was closed by the client, or has nothing to replay.
+// streams which were lost are kept until the resume TTL expires.
+func (c *RPCClient) releaseStream(s resumableStream) {
+	token := s.ResumeToken()
+	if token == "" {
+		return
+	}
+	lost := !errors.Is(s.Err(), psrpc.ErrStreamClosed) && len(s.Unacked()) != 0
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if e, ok := c.resumableStreams[token]; ok && e.stream == s {
+		if lost {
+			c.resumableStreams[token] = resumableEntry{stream: s, expiry: now.Add(c.StreamResumeTTL)}
+		} else {
+			delete(c.resumableStreams, token)
+		}
+	}
+
+	if now.Sub(c.lastResumePrune) > c.StreamResumeTTL {
+		c.lastResumePrune = now
+		for k, e := range c.resumableStreams {
+			if !e.expiry.IsZero() && now.After(e.expiry) {
+				delete(c.resumableStreams, k)
+			}
+		}
+	}
+}
+
 type clientStream struct {
 	c *RPCClient
 	i *info.RequestInfo
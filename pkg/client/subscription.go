@@ -34,7 +34,7 @@ func Join[ResponseType proto.Message](
 	}
 
 	i := c.GetInfo(rpc, topic)
-	sub, err := bus.Subscribe[ResponseType](ctx, c.bus, i.GetRPCChannel(), c.ChannelSize)
+	sub, err := bus.SubscribeResilient[ResponseType](ctx, c.bus, i.GetRPCChannel(), c.ChannelSize, c.SubscriptionState)
 	if err != nil {
 		return nil, psrpc.NewError(psrpc.Internal, err)
 	}
@@ -52,7 +52,7 @@ func JoinQueue[ResponseType proto.Message](
 	}
 
 	i := c.GetInfo(rpc, topic)
-	sub, err := bus.SubscribeQueue[ResponseType](ctx, c.bus, i.GetRPCChannel(), c.ChannelSize)
+	sub, err := bus.SubscribeQueueResilient[ResponseType](ctx, c.bus, i.GetRPCChannel(), c.ChannelSize, c.SubscriptionState)
 	if err != nil {
 		return nil, psrpc.NewError(psrpc.Internal, err)
 	}
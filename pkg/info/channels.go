@@ -62,6 +62,14 @@ func (i *RequestInfo) GetRPCChannel() bus.Channel {
 	}
 }
 
+func (i *RequestInfo) GetServerRPCChannel(serverID string) bus.Channel {
+	return bus.Channel{
+		Legacy: formatChannel('|', i.Service, serverID, i.Method, i.Topic, "REQ"),
+		Server: formatClientChannel(i.Service, serverID, "REQ"),
+		Local:  formatChannel('.', i.Method, i.Topic, "REQ"),
+	}
+}
+
 func (i *RequestInfo) GetHandlerKey() string {
 	return formatChannel('.', i.Method, i.Topic)
 }
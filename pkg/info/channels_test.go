@@ -41,6 +41,9 @@ func TestChannelFormatters(t *testing.T) {
 	require.Equal(t, "foo|bar|REQ", i.GetRPCChannel().Legacy)
 	require.Equal(t, "SRV.foo", i.GetRPCChannel().Server)
 	require.Equal(t, "bar.REQ", i.GetRPCChannel().Local)
+	require.Equal(t, "foo|baz|bar|REQ", i.GetServerRPCChannel("baz").Legacy)
+	require.Equal(t, "CLI.foo.baz.REQ", i.GetServerRPCChannel("baz").Server)
+	require.Equal(t, "bar.REQ", i.GetServerRPCChannel("baz").Local)
 	require.Equal(t, "foo|bar|RCLAIM", i.GetClaimResponseChannel().Legacy)
 	require.Equal(t, "SRV.foo", i.GetClaimResponseChannel().Server)
 	require.Equal(t, "bar.RCLAIM", i.GetClaimResponseChannel().Local)
@@ -54,6 +57,9 @@ func TestChannelFormatters(t *testing.T) {
 	require.Equal(t, "SRV.foo.a.b.c", i.GetRPCChannel().Server)
 	require.Equal(t, "bar.REQ", i.GetRPCChannel().Local)
 	require.Equal(t, "bar.a.b.c", i.GetHandlerKey())
+	require.Equal(t, "foo|baz|bar|a|b|c|REQ", i.GetServerRPCChannel("baz").Legacy)
+	require.Equal(t, "CLI.foo.baz.REQ", i.GetServerRPCChannel("baz").Server)
+	require.Equal(t, "bar.a.b.c.REQ", i.GetServerRPCChannel("baz").Local)
 	require.Equal(t, "foo|bar|a|b|c|RCLAIM", i.GetClaimResponseChannel().Legacy)
 	require.Equal(t, "SRV.foo.a.b.c", i.GetClaimResponseChannel().Server)
 	require.Equal(t, "bar.RCLAIM", i.GetClaimResponseChannel().Local)
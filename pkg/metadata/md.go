@@ -0,0 +1,122 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	grpcmd "google.golang.org/grpc/metadata"
+)
+
+const binarySuffix = "-bin"
+
+// MD is metadata with any number of values per key. Keys ending in "-bin" hold binary values, which are
+// stored as raw bytes in the strings.
+type MD map[string][]string
+
+// IsBinaryKey returns true for keys holding binary values
+func IsBinaryKey(key string) bool {
+	return strings.HasSuffix(key, binarySuffix)
+}
+
+// FromMetadata converts string metadata to MD with one value per key
+func FromMetadata(m Metadata) MD {
+	if m == nil {
+		return nil
+	}
+	md := make(MD, len(m))
+	for k, v := range m {
+		md[k] = []string{v}
+	}
+	return md
+}
+
+// FromGRPC converts gRPC metadata
+func FromGRPC(m grpcmd.MD) MD {
+	return MD(m).Copy()
+}
+
+// ToGRPC converts to gRPC metadata, which requires lowercase keys
+func (md MD) ToGRPC() grpcmd.MD {
+	out := make(grpcmd.MD, len(md))
+	for k, vs := range md {
+		k = strings.ToLower(k)
+		out[k] = append(out[k], vs...)
+	}
+	return out
+}
+
+func (md MD) Get(key string) []string {
+	return md[key]
+}
+
+func (md MD) Set(key string, values ...string) {
+	md[key] = values
+}
+
+func (md MD) Append(key string, values ...string) {
+	md[key] = append(md[key], values...)
+}
+
+func (md MD) Copy() MD {
+	if md == nil {
+		return nil
+	}
+	out := make(MD, len(md))
+	for k, vs := range md {
+		out[k] = slices.Clone(vs)
+	}
+	return out
+}
+
+// Strings returns the first value of each text key, for code using string metadata
+func (md MD) Strings() Metadata {
+	if md == nil {
+		return nil
+	}
+	out := make(Metadata, len(md))
+	for k, vs := range md {
+		if len(vs) != 0 && !IsBinaryKey(k) {
+			out[k] = vs[0]
+		}
+	}
+	return out
+}
+
+// NewContextWithOutgoingMD sets multi-valued metadata on requests made with the context. It takes precedence
+// over string metadata with the same keys.
+func NewContextWithOutgoingMD(ctx context.Context, md MD) context.Context {
+	c, _ := ctx.Value(metadataKey{}).(ctxMD)
+	c.values = md
+	return context.WithValue(ctx, metadataKey{}, c)
+}
+
+// OutgoingContextMD returns the string and multi-valued metadata set on the context
+func OutgoingContextMD(ctx context.Context) MD {
+	c, ok := ctx.Value(metadataKey{}).(ctxMD)
+	if !ok {
+		return nil
+	}
+	md := FromMetadata(OutgoingContextMetadata(ctx))
+	if md == nil {
+		md = MD{}
+	}
+	for k, vs := range c.values {
+		md[k] = slices.Clone(vs)
+	}
+	return md
+}
@@ -0,0 +1,67 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	grpcmd "google.golang.org/grpc/metadata"
+)
+
+func TestOutgoingContextMD(t *testing.T) {
+	ctx := NewContextWithOutgoingMetadata(context.Background(), Metadata{"a": "1", "b": "2"})
+	ctx = NewContextWithOutgoingMD(ctx, MD{"b": {"3", "4"}, "trace-bin": {"\x00\x01"}})
+	ctx = AppendMetadataToOutgoingContext(ctx, "c", "5")
+
+	require.Equal(t, MD{
+		"a":         {"1"},
+		"b":         {"3", "4"},
+		"c":         {"5"},
+		"trace-bin": {"\x00\x01"},
+	}, OutgoingContextMD(ctx))
+	require.Equal(t, Metadata{"a": "1", "b": "2", "c": "5"}, OutgoingContextMetadata(ctx))
+}
+
+func TestGRPCConversion(t *testing.T) {
+	g := grpcmd.Pairs("scope", "read", "scope", "write", "trace-bin", "\x00\x01")
+	md := FromGRPC(g)
+	require.Equal(t, []string{"read", "write"}, md.Get("scope"))
+	require.Equal(t, Metadata{"scope": "read"}, md.Strings())
+
+	md.Append("Scope", "admin")
+	require.ElementsMatch(t, []string{"read", "write", "admin"}, md.ToGRPC().Get("scope"))
+	require.Equal(t, g.Get("trace-bin"), md.ToGRPC().Get("trace-bin"))
+}
+
+func TestPropagatedMetadata(t *testing.T) {
+	policy := &PropagationPolicy{Prefixes: []string{"x-"}}
+	require.Equal(t, context.Background(), NewContextWithPropagatedMetadata(context.Background(), policy))
+
+	ctx := NewContextWithIncomingHeader(context.Background(), &Header{
+		RemoteID: "b",
+		Values:   MD{"x-trace": {"abc"}, "x-request-id": {"123"}, "authorization": {"token"}},
+		Route:    []string{"a"},
+	})
+	ctx = AppendMetadataToOutgoingContext(ctx, "x-request-id", "override")
+	ctx = NewContextWithPropagatedMetadata(ctx, policy)
+
+	require.Equal(t, MD{
+		"x-trace":      {"abc"},
+		"x-request-id": {"override"},
+	}, OutgoingContextMD(ctx))
+	require.Equal(t, []string{"a", "b"}, OutgoingContextRoute(ctx))
+}
@@ -16,9 +16,11 @@ package metadata
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 )
 
 type Metadata map[string]string
@@ -26,16 +28,33 @@ type Metadata map[string]string
 type Header struct {
 	RemoteID string
 	SentAt   time.Time
-	Metadata Metadata
+	Metadata Metadata // first value of each text key
+	Values   MD       // all metadata, including repeated values and binary keys
+	Route    []string // IDs of the nodes a propagated request passed through before RemoteID, starting with the origin
+}
+
+// Hops returns the number of hops the request has taken
+func (h *Header) Hops() int {
+	return len(h.Route) + 1
+}
+
+// ResponseMetadata is returned by a server with its response, or when it closes a stream
+type ResponseMetadata struct {
+	Header  MD
+	Trailer MD
 }
 
 type ctxMD struct {
-	md    Metadata
-	added [][]string
+	md     Metadata
+	added  [][]string
+	values MD
+	route  []string
 }
 
 type headerKey struct{}
 type metadataKey struct{}
+type claimKey struct{}
+type responseKey struct{}
 
 func NewContextWithIncomingHeader(ctx context.Context, head *Header) context.Context {
 	return context.WithValue(ctx, headerKey{}, head)
@@ -50,23 +69,26 @@ func IncomingHeader(ctx context.Context) *Header {
 		RemoteID: head.RemoteID,
 		SentAt:   head.SentAt,
 		Metadata: maps.Clone(head.Metadata),
+		Values:   head.Values.Copy(),
+		Route:    slices.Clone(head.Route),
 	}
 }
 
 func NewContextWithOutgoingMetadata(ctx context.Context, md Metadata) context.Context {
-	return context.WithValue(ctx, metadataKey{}, ctxMD{md: md})
+	c, _ := ctx.Value(metadataKey{}).(ctxMD)
+	return context.WithValue(ctx, metadataKey{}, ctxMD{md: md, values: c.values, route: c.route})
 }
 
 func AppendMetadataToOutgoingContext(ctx context.Context, kv ...string) context.Context {
-	md, ok := ctx.Value(metadataKey{}).(ctxMD)
-	if !ok || md.md == nil {
-		md = ctxMD{md: Metadata{}}
+	md, _ := ctx.Value(metadataKey{}).(ctxMD)
+	if md.md == nil {
+		md.md = Metadata{}
 	}
 	added := make([][]string, len(md.added)+1)
 	copy(added, md.added)
 	added[len(added)-1] = make([]string, len(kv))
 	copy(added[len(added)-1], kv)
-	return context.WithValue(ctx, metadataKey{}, ctxMD{md.md, added})
+	return context.WithValue(ctx, metadataKey{}, ctxMD{md.md, added, md.values, md.route})
 }
 
 func OutgoingContextMetadata(ctx context.Context) Metadata {
@@ -82,3 +104,138 @@ func OutgoingContextMetadata(ctx context.Context) Metadata {
 	}
 	return clone
 }
+
+// NewContextWithClaimMetadata returns a context for scoring a request, and the metadata set on it
+// with SetClaimMetadata
+func NewContextWithClaimMetadata(ctx context.Context) (context.Context, *Metadata) {
+	md := new(Metadata)
+	return context.WithValue(ctx, claimKey{}, md), md
+}
+
+// SetClaimMetadata attaches metadata to the server's claim for a request. It should be called from
+// an affinity function, and has no effect elsewhere.
+func SetClaimMetadata(ctx context.Context, md Metadata) {
+	claim, ok := ctx.Value(claimKey{}).(*Metadata)
+	if !ok {
+		return
+	}
+	if *claim == nil {
+		*claim = Metadata{}
+	}
+	maps.Copy(*claim, md)
+}
+
+// NewContextWithResponseMetadata returns a context for handling a request, and the metadata set on it
+// with SetHeader and SetTrailer
+func NewContextWithResponseMetadata(ctx context.Context) (context.Context, *ResponseMetadata) {
+	md := &ResponseMetadata{}
+	return context.WithValue(ctx, responseKey{}, md), md
+}
+
+// ResponseMetadataFromContext returns the response metadata set on a server's context, or nil outside of
+// a handler
+func ResponseMetadataFromContext(ctx context.Context) *ResponseMetadata {
+	md, _ := ctx.Value(responseKey{}).(*ResponseMetadata)
+	return md
+}
+
+// SetHeader adds metadata to the server's response, appending to any values already set for the same keys.
+// It should be called from a handler, and has no effect elsewhere.
+func SetHeader(ctx context.Context, md MD) {
+	if res := ResponseMetadataFromContext(ctx); res != nil {
+		res.Header = merge(res.Header, md)
+	}
+}
+
+// SetTrailer adds metadata to the server's response. For streams, it is sent when the server closes the
+// stream, so it can be set after the last message.
+func SetTrailer(ctx context.Context, md MD) {
+	if res := ResponseMetadataFromContext(ctx); res != nil {
+		res.Trailer = merge(res.Trailer, md)
+	}
+}
+
+func merge(dst, src MD) MD {
+	if dst == nil {
+		dst = MD{}
+	}
+	for k, vs := range src {
+		dst.Append(k, vs...)
+	}
+	return dst
+}
+
+// PropagationPolicy selects the incoming metadata which is forwarded when a handler makes requests to other
+// services
+type PropagationPolicy struct {
+	Keys     []string // keys to propagate
+	Prefixes []string // key prefixes to propagate, such as "x-"
+}
+
+func (p *PropagationPolicy) Match(key string) bool {
+	if slices.Contains(p.Keys, key) {
+		return true
+	}
+	for _, prefix := range p.Prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// PropagateMetadata adds incoming metadata matching the policy to outgoing metadata, and returns the route
+// of the outgoing request. Outgoing values take precedence over propagated ones.
+func PropagateMetadata(ctx context.Context, md MD, policy *PropagationPolicy) (MD, []string) {
+	head, ok := ctx.Value(headerKey{}).(*Header)
+	if !ok {
+		return md, nil
+	}
+
+	incoming := head.Values
+	if incoming == nil {
+		incoming = FromMetadata(head.Metadata)
+	}
+	for k, vs := range incoming {
+		if _, ok := md[k]; !ok && policy.Match(k) {
+			if md == nil {
+				md = MD{}
+			}
+			md[k] = slices.Clone(vs)
+		}
+	}
+
+	route := make([]string, 0, len(head.Route)+1)
+	route = append(route, head.Route...)
+	return md, append(route, head.RemoteID)
+}
+
+// NewContextWithPropagatedMetadata returns a context for making requests from a handler, with the incoming
+// metadata matching the policy added to the outgoing metadata, and the route of the incoming request
+func NewContextWithPropagatedMetadata(ctx context.Context, policy *PropagationPolicy) context.Context {
+	outgoing := OutgoingContextMD(ctx)
+	md, route := PropagateMetadata(ctx, outgoing.Copy(), policy)
+	if route == nil {
+		return ctx
+	}
+
+	c, _ := ctx.Value(metadataKey{}).(ctxMD)
+	values := c.values.Copy()
+	for k, vs := range md {
+		if _, ok := outgoing[k]; !ok {
+			if values == nil {
+				values = MD{}
+			}
+			values[k] = vs
+		}
+	}
+	c.values = values
+	c.route = route
+	return context.WithValue(ctx, metadataKey{}, c)
+}
+
+// OutgoingContextRoute returns the route set on the context by NewContextWithPropagatedMetadata
+func OutgoingContextRoute(ctx context.Context) []string {
+	c, _ := ctx.Value(metadataKey{}).(ctxMD)
+	return slices.Clone(c.route)
+}
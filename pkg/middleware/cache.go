@@ -0,0 +1,210 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/metadata"
+)
+
+// CacheControlKey is the response header used by servers to control how clients cache a response
+const CacheControlKey = "cache-control"
+
+const DefaultCacheMaxEntries = 1000
+
+type CacheOptions struct {
+	// TTL is the lifetime of responses without a cache hint. If zero, only responses with a max-age are cached.
+	TTL time.Duration
+	// MaxEntries (default 1000) limits the number of cached responses, evicting the least recently used
+	MaxEntries int
+	// StaleWhileRevalidate serves expired responses for up to this long, while they are refreshed in the background
+	StaleWhileRevalidate time.Duration
+}
+
+// SetCacheMaxAge lets clients with WithResponseCache cache the response for up to maxAge
+func SetCacheMaxAge(ctx context.Context, maxAge time.Duration) {
+	metadata.SetHeader(ctx, metadata.MD{CacheControlKey: {"max-age=" + strconv.Itoa(int(maxAge.Seconds()))}})
+}
+
+// SetNoStore prevents clients with WithResponseCache from caching the response
+func SetNoStore(ctx context.Context) {
+	metadata.SetHeader(ctx, metadata.MD{CacheControlKey: {"no-store"}})
+}
+
+// parseCacheControl returns the lifetime of a response, or false if it should not be cached
+func parseCacheControl(header metadata.MD, ttl time.Duration) (time.Duration, bool) {
+	for _, directive := range strings.Split(strings.Join(header.Get(CacheControlKey), ","), ",") {
+		directive = strings.TrimSpace(directive)
+		if directive == "no-store" {
+			return 0, false
+		}
+		if v, ok := strings.CutPrefix(directive, "max-age="); ok {
+			if seconds, err := strconv.Atoi(v); err == nil {
+				ttl = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return ttl, ttl > 0
+}
+
+// WithResponseCache caches successful unary responses by service, method, topic, request, metadata and routing
+// options, so responses are only shared between callers with the same credentials. Requests with a custom selection
+// function or request interceptors are not cached. Servers can set the lifetime of each response with
+// SetCacheMaxAge, or prevent caching with SetNoStore.
+func WithResponseCache(opts CacheOptions) psrpc.ClientOption {
+	return psrpc.WithClientRPCInterceptors(NewResponseCacheInterceptor(opts))
+}
+
+func NewResponseCacheInterceptor(opts CacheOptions) psrpc.ClientRPCInterceptor {
+	if opts.MaxEntries == 0 {
+		opts.MaxEntries = DefaultCacheMaxEntries
+	}
+	c := &responseCache{
+		opts:    opts,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+	return c.interceptor
+}
+
+type responseCache struct {
+	opts CacheOptions
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type cacheEntry struct {
+	key        string
+	res        proto.Message
+	md         metadata.ResponseMetadata
+	expiry     time.Time
+	refreshing bool
+}
+
+func (c *responseCache) interceptor(rpcInfo psrpc.RPCInfo, next psrpc.ClientRPCHandler) psrpc.ClientRPCHandler {
+	return func(ctx context.Context, req proto.Message, opts ...psrpc.RequestOption) (proto.Message, error) {
+		o := getRequestOpts(opts)
+		key, ok := requestKey(ctx, rpcInfo, req, o)
+		if !ok {
+			return next(ctx, req, opts...)
+		}
+
+		if res, md, ok := c.get(key, func() { c.fetch(context.WithoutCancel(ctx), key, req, next, opts) }); ok {
+			if o.ResponseMetadata != nil {
+				*o.ResponseMetadata = md
+			}
+			return res, nil
+		}
+
+		res, md, err := c.fetch(ctx, key, req, next, opts)
+		if o.ResponseMetadata != nil {
+			*o.ResponseMetadata = md
+		}
+		return res, err
+	}
+}
+
+// get returns a copy of a cached response. Expired responses within the stale window are returned once refresh
+// has been started.
+func (c *responseCache) get(key string, refresh func()) (proto.Message, metadata.ResponseMetadata, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, metadata.ResponseMetadata{}, false
+	}
+	e := el.Value.(*cacheEntry)
+
+	now := time.Now()
+	if now.After(e.expiry) {
+		if now.After(e.expiry.Add(c.opts.StaleWhileRevalidate)) {
+			c.remove(el)
+			return nil, metadata.ResponseMetadata{}, false
+		}
+		if !e.refreshing {
+			e.refreshing = true
+			go refresh()
+		}
+	}
+
+	c.lru.MoveToFront(el)
+	return proto.Clone(e.res), e.md, true
+}
+
+func (c *responseCache) fetch(
+	ctx context.Context,
+	key string,
+	req proto.Message,
+	next psrpc.ClientRPCHandler,
+	opts []psrpc.RequestOption,
+) (proto.Message, metadata.ResponseMetadata, error) {
+	var md metadata.ResponseMetadata
+	res, err := next(ctx, req, append(opts, psrpc.WithResponseMetadata(&md))...)
+
+	ttl, ok := parseCacheControl(md.Header, c.opts.TTL)
+	if err != nil || !ok {
+		c.mu.Lock()
+		if el, ok := c.entries[key]; ok {
+			if err != nil {
+				el.Value.(*cacheEntry).refreshing = false
+			} else {
+				c.remove(el)
+			}
+		}
+		c.mu.Unlock()
+		return res, md, err
+	}
+
+	c.set(&cacheEntry{
+		key:    key,
+		res:    proto.Clone(res),
+		md:     md,
+		expiry: time.Now().Add(ttl),
+	})
+	return res, md, nil
+}
+
+func (c *responseCache) set(e *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[e.key]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	c.entries[e.key] = c.lru.PushFront(e)
+	for c.lru.Len() > c.opts.MaxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *responseCache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry).key)
+}
@@ -0,0 +1,343 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/metadata"
+)
+
+// WithRequestCoalescing shares one request between concurrent callers making the same call, with the same method,
+// topic, request message, metadata and routing options. Each caller receives its own copy of the responses.
+// Requests with a custom selection function or request interceptors are never shared. Idempotency keys are ignored,
+// so calls are still shared behind the retry middleware. Callers joining a multi-RPC stop at their own context or
+// timeout, and the responses replayed to them do not carry response metadata.
+func WithRequestCoalescing() psrpc.ClientOption {
+	c := &coalescer{
+		calls: make(map[string]*coalescedCall),
+		multi: make(map[string]*coalescedMultiCall),
+	}
+	return psrpc.WithClientOptions(
+		psrpc.WithClientRPCInterceptors(c.rpcInterceptor),
+		psrpc.WithClientMultiRPCInterceptors(c.multiRPCInterceptor),
+	)
+}
+
+type coalescer struct {
+	mu    sync.Mutex
+	calls map[string]*coalescedCall
+	multi map[string]*coalescedMultiCall
+}
+
+type coalescedCall struct {
+	done chan struct{}
+	res  proto.Message
+	md   metadata.ResponseMetadata
+	err  error
+}
+
+// coalescedMultiCall keeps the responses received so far, so callers joining late receive every response
+type coalescedMultiCall struct {
+	mu        sync.Mutex
+	responses []coalescedResponse
+	joined    []chan struct{}
+	closed    bool
+}
+
+type coalescedResponse struct {
+	msg proto.Message
+	err error
+}
+
+func getRequestOpts(opts []psrpc.RequestOption) psrpc.RequestOpts {
+	var o psrpc.RequestOpts
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// requestKey identifies requests which can share a response. It includes the outgoing and incoming metadata,
+// since either can carry credentials, and the options which change where the request is routed. It returns false
+// for requests which can't be compared.
+func requestKey(ctx context.Context, rpcInfo psrpc.RPCInfo, req proto.Message, o psrpc.RequestOpts) (string, bool) {
+	if o.SelectionOpts.SelectionFunc != nil || o.SelectionOpts.KeyedSelectionFunc != nil || len(o.Interceptors) != 0 {
+		return "", false
+	}
+
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return "", false
+	}
+
+	h := sha256.New()
+	writeKeyFields(h, rpcInfo.Service, rpcInfo.Method, strings.Join(rpcInfo.Topic, "."), string(b))
+	writeKeyFields(h, o.RoutingKey, o.TargetServer, fmt.Sprint(o.ExpectedResponses))
+	writeKeyFields(h, o.ExpectedServers...)
+	writeKeyFields(h, fmt.Sprint(
+		o.SelectionOpts.MinimumAffinity,
+		o.SelectionOpts.MaximumAffinity,
+		o.SelectionOpts.AcceptFirstAvailable,
+		o.SelectionOpts.AffinityTimeout,
+		o.SelectionOpts.ShortCircuitTimeout,
+	))
+	writeKeyMetadata(h, metadata.OutgoingContextMD(ctx))
+	if head := metadata.IncomingHeader(ctx); head != nil {
+		if head.Values != nil {
+			writeKeyMetadata(h, head.Values)
+		} else {
+			writeKeyMetadata(h, metadata.FromMetadata(head.Metadata))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// writeKeyFields writes a count followed by length prefixed values, so different fields can't produce the same key
+func writeKeyFields(h hash.Hash, fields ...string) {
+	fmt.Fprintf(h, "%d;", len(fields))
+	for _, f := range fields {
+		fmt.Fprintf(h, "%d:%s", len(f), f)
+	}
+}
+
+func writeKeyMetadata(h hash.Hash, md metadata.MD) {
+	keys := maps.Keys(md)
+	slices.Sort(keys)
+	writeKeyFields(h, keys...)
+	for _, k := range keys {
+		writeKeyFields(h, md[k]...)
+	}
+}
+
+func (c *coalescer) rpcInterceptor(rpcInfo psrpc.RPCInfo, next psrpc.ClientRPCHandler) psrpc.ClientRPCHandler {
+	return func(ctx context.Context, req proto.Message, opts ...psrpc.RequestOption) (proto.Message, error) {
+		o := getRequestOpts(opts)
+		key, ok := requestKey(ctx, rpcInfo, req, o)
+		if !ok {
+			return next(ctx, req, opts...)
+		}
+
+		c.mu.Lock()
+		call, ok := c.calls[key]
+		if !ok {
+			call = &coalescedCall{done: make(chan struct{})}
+			c.calls[key] = call
+
+			// the shared request outlives the caller that started it
+			go func() {
+				call.res, call.err = next(context.WithoutCancel(ctx), req, append(opts, psrpc.WithResponseMetadata(&call.md))...)
+
+				c.mu.Lock()
+				delete(c.calls, key)
+				c.mu.Unlock()
+				close(call.done)
+			}()
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-call.done:
+			if o.ResponseMetadata != nil {
+				*o.ResponseMetadata = metadata.ResponseMetadata{
+					Header:  call.md.Header.Copy(),
+					Trailer: call.md.Trailer.Copy(),
+				}
+			}
+			if call.res == nil {
+				return nil, call.err
+			}
+			return proto.Clone(call.res), call.err
+
+		case <-ctx.Done():
+			err := ctx.Err()
+			if errors.Is(err, context.Canceled) {
+				err = psrpc.ErrRequestCanceled
+			} else if errors.Is(err, context.DeadlineExceeded) {
+				err = psrpc.ErrRequestTimedOut
+			}
+			return nil, err
+		}
+	}
+}
+
+func (c *coalescer) multiRPCInterceptor(rpcInfo psrpc.RPCInfo, next psrpc.ClientMultiRPCHandler) psrpc.ClientMultiRPCHandler {
+	return &coalescedMultiRPC{
+		ClientMultiRPCHandler: next,
+		c:                     c,
+		info:                  rpcInfo,
+	}
+}
+
+type coalescedMultiRPC struct {
+	psrpc.ClientMultiRPCHandler
+	c    *coalescer
+	info psrpc.RPCInfo
+
+	// set on the caller whose request is sent
+	key  string
+	call *coalescedMultiCall
+}
+
+func (m *coalescedMultiRPC) Send(ctx context.Context, req proto.Message, opts ...psrpc.RequestOption) error {
+	o := getRequestOpts(opts)
+	key, ok := requestKey(ctx, m.info, req, o)
+	if !ok {
+		return m.ClientMultiRPCHandler.Send(ctx, req, opts...)
+	}
+
+	m.c.mu.Lock()
+	call, ok := m.c.multi[key]
+	joined := ok && call.join(ctx, o.Timeout, m.ClientMultiRPCHandler)
+	m.c.mu.Unlock()
+	if joined {
+		return nil
+	}
+
+	m.call = &coalescedMultiCall{}
+	if err := m.ClientMultiRPCHandler.Send(ctx, req, opts...); err != nil {
+		return err
+	}
+
+	// other callers can join once the request was sent, unless it has already completed
+	m.c.mu.Lock()
+	m.call.mu.Lock()
+	if !m.call.closed {
+		m.key = key
+		m.c.multi[key] = m.call
+	}
+	m.call.mu.Unlock()
+	m.c.mu.Unlock()
+	return nil
+}
+
+func (m *coalescedMultiRPC) Recv(msg proto.Message, err error) {
+	if m.call != nil {
+		m.call.add(coalescedResponse{msg, err})
+	}
+	m.ClientMultiRPCHandler.Recv(msg, err)
+}
+
+func (m *coalescedMultiRPC) Close() {
+	if m.call != nil {
+		m.c.mu.Lock()
+		if m.c.multi[m.key] == m.call {
+			delete(m.c.multi, m.key)
+		}
+		m.c.mu.Unlock()
+
+		m.call.close()
+	}
+	m.ClientMultiRPCHandler.Close()
+}
+
+// join subscribes a caller making the same request to its responses. The responses are passed to the caller's
+// handler in order by its own goroutine, starting with the ones received so far, so a slow caller doesn't hold up
+// the request or the other callers. The caller's handler is closed early when its context is done, or after
+// its own timeout if it set one.
+func (c *coalescedMultiCall) join(ctx context.Context, timeout time.Duration, h psrpc.ClientMultiRPCHandler) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return false
+	}
+	notify := make(chan struct{}, 1)
+	c.joined = append(c.joined, notify)
+	go c.forward(ctx, timeout, h, notify)
+	return true
+}
+
+func (c *coalescedMultiCall) forward(
+	ctx context.Context,
+	timeout time.Duration,
+	h psrpc.ClientMultiRPCHandler,
+	notify <-chan struct{},
+) {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	var next int
+	for {
+		c.mu.Lock()
+		pending := c.responses[next:]
+		closed := c.closed
+		c.mu.Unlock()
+
+		for _, res := range pending {
+			h.Recv(proto.Clone(res.msg), res.err)
+		}
+		next += len(pending)
+
+		if closed {
+			h.Close()
+			return
+		}
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			h.Close()
+			return
+		case <-expired:
+			h.Close()
+			return
+		}
+	}
+}
+
+func (c *coalescedMultiCall) add(res coalescedResponse) {
+	c.mu.Lock()
+	c.responses = append(c.responses, res)
+	joined := c.joined
+	c.mu.Unlock()
+
+	notifyJoined(joined)
+}
+
+// close stops callers from joining the request, and closes their handlers once they have received every response
+func (c *coalescedMultiCall) close() {
+	c.mu.Lock()
+	c.closed = true
+	joined := c.joined
+	c.mu.Unlock()
+
+	notifyJoined(joined)
+}
+
+func notifyJoined(joined []chan struct{}) {
+	for _, notify := range joined {
+		select {
+		case notify <- struct{}{}:
+		default:
+		}
+	}
+}
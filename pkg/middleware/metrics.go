@@ -16,6 +16,7 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"go.uber.org/atomic"
@@ -52,6 +53,12 @@ type MetricsObserver interface {
 	OnStreamClose(role MetricRole, rpcInfo psrpc.RPCInfo)
 }
 
+// StreamKeepaliveObserver can be implemented by a MetricsObserver to record streams closed by keepalive
+// because the peer stopped responding
+type StreamKeepaliveObserver interface {
+	OnStreamPeerUnresponsive(role MetricRole, rpcInfo psrpc.RPCInfo)
+}
+
 func WithClientMetrics(observer MetricsObserver) psrpc.ClientOption {
 	return psrpc.WithClientOptions(
 		psrpc.WithClientRPCInterceptors(newClientRPCMetricsInterceptor(observer)),
@@ -132,6 +139,9 @@ func (s *streamMetricsInterceptor) Send(msg proto.Message, opts ...psrpc.StreamO
 
 func (s *streamMetricsInterceptor) Close(cause error) error {
 	if !s.closed.Swap(true) {
+		if o, ok := s.observer.(StreamKeepaliveObserver); ok && errors.Is(cause, psrpc.ErrPeerUnresponsive) {
+			o.OnStreamPeerUnresponsive(s.role, s.info)
+		}
 		s.observer.OnStreamClose(s.role, s.info)
 	}
 	return s.StreamHandler.Close(cause)
@@ -17,28 +17,73 @@ package middleware
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"google.golang.org/protobuf/proto"
 
 	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/rand"
 )
 
+const DefaultMaxBackoff = 10 * time.Second
+
+// DefaultRetryCodes are the error codes retried when RetryOptions.Codes is not set
+var DefaultRetryCodes = map[psrpc.ErrorCode]bool{
+	psrpc.DeadlineExceeded: true,
+	psrpc.Unavailable:      true,
+}
+
 type RetryOptions struct {
-	MaxAttempts        int
-	Timeout            time.Duration
-	Backoff            time.Duration
-	IsRecoverable      func(err error) bool
+	MaxAttempts int
+	// Timeout limits each attempt. It does not grow between attempts.
+	Timeout time.Duration
+	// Backoff is the base delay before retrying. It doubles after each attempt, and each delay is chosen at
+	// random up to the current backoff.
+	Backoff time.Duration
+	// MaxBackoff (default 10s) limits the delay between attempts
+	MaxBackoff time.Duration
+	// Codes (default DefaultRetryCodes) are the error codes which are retried. Errors which are not a
+	// psrpc.Error are never retried.
+	Codes map[psrpc.ErrorCode]bool
+	// Budget limits retries to a fraction of requests. Share one budget between a client's interceptors.
+	Budget *RetryBudget
+	// Methods replaces these options for the methods with matching names
+	Methods map[string]RetryOptions
+
+	IsRecoverable      func(err error) bool                                                                     // will override Codes
 	GetRetryParameters func(err error, attempt int) (retry bool, timeout time.Duration, waitTime time.Duration) // will override the MaxAttempts, Timeout and Backoff parameters
 }
 
+// forMethod returns the options used for a method. Method options without a budget share the default budget.
+func (o RetryOptions) forMethod(method string) RetryOptions {
+	mo, ok := o.Methods[method]
+	if !ok {
+		return o
+	}
+	if mo.Budget == nil {
+		mo.Budget = o.Budget
+	}
+	return mo
+}
+
 func WithRPCRetries(opt RetryOptions) psrpc.ClientOption {
 	return psrpc.WithClientRPCInterceptors(NewRPCRetryInterceptor(opt))
 }
 
+// NewRPCRetryInterceptor retries failed requests. Calls without an idempotency key get one for the logical
+// call, shared by all of its attempts.
 func NewRPCRetryInterceptor(opt RetryOptions) psrpc.ClientRPCInterceptor {
 	return func(rpcInfo psrpc.RPCInfo, next psrpc.ClientRPCHandler) psrpc.ClientRPCHandler {
+		opt := opt.forMethod(rpcInfo.Method)
+
 		return func(ctx context.Context, req proto.Message, opts ...psrpc.RequestOption) (res proto.Message, err error) {
+			// every attempt of a logical call shares an idempotency key, so servers can tell retries apart
+			// from new requests. a key is only generated when the caller didn't pass one.
+			if getRequestOpts(opts).IdempotencyKey == "" {
+				opts = append(opts[:len(opts):len(opts)], psrpc.WithIdempotencyKey(rand.NewString()))
+			}
+
 			err = retry(opt, ctx.Done(), func(timeout time.Duration) error {
 				nextOpts := opts
 				if timeout > 0 {
@@ -55,25 +100,33 @@ func NewRPCRetryInterceptor(opt RetryOptions) psrpc.ClientRPCInterceptor {
 	}
 }
 
-func isTimeout(err error) bool {
-	var e psrpc.Error
-	if !errors.As(err, &e) {
-		return true
+func isRetryableCode(codes map[psrpc.ErrorCode]bool) func(err error) bool {
+	if codes == nil {
+		codes = DefaultRetryCodes
+	}
+	return func(err error) bool {
+		var e psrpc.Error
+		if !errors.As(err, &e) {
+			return false
+		}
+		return codes[e.Code()]
 	}
-	return e.Code() == psrpc.DeadlineExceeded || e.Code() == psrpc.Unavailable
 }
 
 func getRetryWithBackoffParameters(o RetryOptions) func(err error, attempt int) (retry bool, timeout time.Duration, waitTime time.Duration) {
 	timeout := o.Timeout
+	maxBackoff := o.MaxBackoff
+	if maxBackoff == 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
 
 	return func(err error, attempt int) (bool, time.Duration, time.Duration) {
 		if !o.IsRecoverable(err) || attempt == o.MaxAttempts {
 			return false, 0, 0
 		}
 
-		timeout += o.Backoff
-
-		return true, timeout, 0
+		// every attempt gets the same timeout. only the delay between attempts grows.
+		return true, timeout, jitteredBackoff(o.Backoff, maxBackoff, attempt)
 	}
 }
 
@@ -81,13 +134,15 @@ func retry(opt RetryOptions, done <-chan struct{}, fn func(timeout time.Duration
 	timeout := opt.Timeout
 	attempt := 1
 	if opt.IsRecoverable == nil {
-		opt.IsRecoverable = isTimeout
+		opt.IsRecoverable = isRetryableCode(opt.Codes)
 	}
 
 	if opt.GetRetryParameters == nil {
 		opt.GetRetryParameters = getRetryWithBackoffParameters(opt)
 	}
 
+	opt.Budget.deposit()
+
 	for {
 		err := fn(timeout)
 		if err == nil {
@@ -97,16 +152,18 @@ func retry(opt RetryOptions, done <-chan struct{}, fn func(timeout time.Duration
 		var retry bool
 		var waitTime time.Duration
 		retry, timeout, waitTime = opt.GetRetryParameters(err, attempt)
-		if !retry {
+		if !retry || !opt.Budget.withdraw() {
 			return err
 		}
 
 		attempt++
 
+		wait := time.NewTimer(waitTime)
 		select {
 		case <-done:
+			wait.Stop()
 			return psrpc.ErrRequestCanceled
-		case <-time.After(waitTime):
+		case <-wait.C:
 		}
 	}
 }
@@ -119,18 +176,25 @@ func NewStreamRetryInterceptor(opt RetryOptions) psrpc.StreamInterceptor {
 	return func(rpcInfo psrpc.RPCInfo, next psrpc.StreamHandler) psrpc.StreamHandler {
 		return &streamRetryInterceptor{
 			StreamHandler: next,
-			opt:           opt,
+			opt:           opt.forMethod(rpcInfo.Method),
+			closed:        make(chan struct{}),
 		}
 	}
 }
 
 type streamRetryInterceptor struct {
 	psrpc.StreamHandler
-	opt RetryOptions
+	opt       RetryOptions
+	closeOnce sync.Once
+	closed    chan struct{}
 }
 
 func (s *streamRetryInterceptor) Send(msg proto.Message, opts ...psrpc.StreamOption) (err error) {
-	return retry(s.opt, nil, func(timeout time.Duration) error {
+	// every attempt carries the message's sequence number, so the peer drops attempts it already received
+	var seq uint64
+	opts = append(opts[:len(opts):len(opts)], psrpc.WithSeq(&seq))
+
+	return retry(s.opt, s.closed, func(timeout time.Duration) error {
 		nextOpts := opts
 		if timeout > 0 {
 			nextOpts = make([]psrpc.StreamOption, len(opts)+1)
@@ -141,3 +205,9 @@ func (s *streamRetryInterceptor) Send(msg proto.Message, opts ...psrpc.StreamOpt
 		return s.StreamHandler.Send(msg, nextOpts...)
 	})
 }
+
+// Close stops retries waiting to resend. It is called when either side closes the stream.
+func (s *streamRetryInterceptor) Close(cause error) error {
+	s.closeOnce.Do(func() { close(s.closed) })
+	return s.StreamHandler.Close(cause)
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RetryBudget is a token bucket which limits retries to a fraction of a client's requests, so that retries
+// cannot multiply the load on a service which is already failing. Each request adds Ratio tokens, each retry
+// takes one, and MinPerSecond tokens are added every second so clients with little traffic can still retry.
+type RetryBudget struct {
+	ratio        float64
+	minPerSecond float64
+	capacity     float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// retryBudgetWindow is the time over which unused tokens accumulate
+const retryBudgetWindow = 10
+
+// NewRetryBudget allows ratio retries per request, e.g. 0.1 allows retrying 10% of requests, plus
+// minPerSecond retries each second. Unused tokens accumulate for up to ten seconds.
+func NewRetryBudget(ratio, minPerSecond float64) *RetryBudget {
+	capacity := retryBudgetWindow * max(minPerSecond, 1)
+	return &RetryBudget{
+		ratio:        ratio,
+		minPerSecond: minPerSecond,
+		capacity:     capacity,
+		tokens:       capacity,
+		last:         time.Now(),
+	}
+}
+
+func (b *RetryBudget) deposit() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	b.tokens = min(b.tokens+b.ratio, b.capacity)
+}
+
+func (b *RetryBudget) withdraw() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (b *RetryBudget) refill() {
+	now := time.Now()
+	b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*b.minPerSecond, b.capacity)
+	b.last = now
+}
+
+// jitteredBackoff returns a random delay between 0 and base doubled after each attempt, capped at limit
+func jitteredBackoff(base, limit time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+
+	backoff := base
+	for i := 1; i < attempt && backoff < limit; i++ {
+		backoff *= 2
+	}
+	return time.Duration(rand.Int63n(int64(min(backoff, limit)) + 1))
+}
@@ -64,10 +64,8 @@ func TestRetryBackoff(t *testing.T) {
 
 		require.Equal(t, ro.MaxAttempts, len(timeouts))
 
-		expectedTimeout := ro.Timeout
 		for _, timeout := range timeouts {
-			require.Equal(t, expectedTimeout, timeout)
-			expectedTimeout += ro.Backoff
+			require.Equal(t, ro.Timeout, timeout)
 		}
 	})
 
@@ -116,3 +114,126 @@ func TestRetryBackoff(t *testing.T) {
 		}
 	})
 }
+
+func TestRetryPolicy(t *testing.T) {
+	countAttempts := func(opt RetryOptions, method string, err error) int {
+		var attempts int
+		ri := NewRPCRetryInterceptor(opt)
+		h := ri(psrpc.RPCInfo{Method: method}, func(ctx context.Context, req proto.Message, opts ...psrpc.RequestOption) (proto.Message, error) {
+			attempts++
+			return nil, err
+		})
+		_, _ = h(context.Background(), nil)
+		return attempts
+	}
+
+	t.Run("Codes", func(t *testing.T) {
+		ro := RetryOptions{MaxAttempts: 3}
+		require.Equal(t, 3, countAttempts(ro, "", psrpc.ErrNoResponse))
+		require.Equal(t, 1, countAttempts(ro, "", psrpc.NewErrorf(psrpc.InvalidArgument, "invalid")))
+		require.Equal(t, 1, countAttempts(ro, "", errors.New("test error")))
+
+		ro.Codes = map[psrpc.ErrorCode]bool{psrpc.ResourceExhausted: true}
+		require.Equal(t, 3, countAttempts(ro, "", psrpc.ErrServerOverloaded))
+		require.Equal(t, 1, countAttempts(ro, "", psrpc.ErrNoResponse))
+	})
+
+	t.Run("Methods", func(t *testing.T) {
+		ro := RetryOptions{
+			MaxAttempts: 3,
+			Methods: map[string]RetryOptions{
+				"Create": {MaxAttempts: 1},
+			},
+		}
+		require.Equal(t, 3, countAttempts(ro, "Get", psrpc.ErrNoResponse))
+		require.Equal(t, 1, countAttempts(ro, "Create", psrpc.ErrNoResponse))
+	})
+
+	t.Run("Budget", func(t *testing.T) {
+		ro := RetryOptions{
+			MaxAttempts: 3,
+			Budget:      NewRetryBudget(0, 0),
+		}
+		// the budget starts with 10 tokens, enough to retry 5 requests twice
+		var attempts int
+		for i := 0; i < 10; i++ {
+			attempts += countAttempts(ro, "", psrpc.ErrNoResponse)
+		}
+		require.Equal(t, 20, attempts)
+
+		ro.Budget = NewRetryBudget(0.5, 0)
+		ro.Budget.tokens = 0
+		require.Equal(t, 1, countAttempts(ro, "", psrpc.ErrNoResponse))
+		require.Equal(t, 2, countAttempts(ro, "", psrpc.ErrNoResponse))
+	})
+
+	t.Run("IdempotencyKey", func(t *testing.T) {
+		keys := func(opts ...psrpc.RequestOption) []string {
+			var keys []string
+			ri := NewRPCRetryInterceptor(RetryOptions{MaxAttempts: 2})
+			h := ri(psrpc.RPCInfo{}, func(ctx context.Context, req proto.Message, opts ...psrpc.RequestOption) (proto.Message, error) {
+				keys = append(keys, getRequestOpts(opts).IdempotencyKey)
+				return nil, psrpc.ErrNoResponse
+			})
+			_, _ = h(context.Background(), nil, opts...)
+			return keys
+		}
+
+		generated := keys()
+		require.Len(t, generated, 2)
+		require.NotEmpty(t, generated[0])
+		require.Equal(t, generated[0], generated[1])
+		require.NotEqual(t, generated[0], keys()[0])
+
+		require.Equal(t, []string{"key", "key"}, keys(psrpc.WithIdempotencyKey("key")))
+	})
+
+	t.Run("Backoff", func(t *testing.T) {
+		for attempt := 1; attempt < 100; attempt++ {
+			backoff := jitteredBackoff(100*time.Millisecond, time.Second, attempt)
+			require.GreaterOrEqual(t, backoff, time.Duration(0))
+			require.LessOrEqual(t, backoff, min(100*time.Millisecond<<min(attempt-1, 4), time.Second))
+		}
+		require.Equal(t, time.Duration(0), jitteredBackoff(0, time.Second, 3))
+	})
+
+	t.Run("StreamClose", func(t *testing.T) {
+		ro := RetryOptions{
+			MaxAttempts: 3,
+			Backoff:     time.Minute,
+			MaxBackoff:  time.Minute,
+		}
+		si := NewStreamRetryInterceptor(ro)
+		h := si(psrpc.RPCInfo{}, &failingStreamHandler{err: psrpc.ErrNoResponse})
+
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- h.Send(nil)
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		require.NoError(t, h.Close(nil))
+		select {
+		case err := <-errChan:
+			require.ErrorIs(t, err, psrpc.ErrRequestCanceled)
+		case <-time.After(time.Second):
+			t.Fatal("send was not canceled by close")
+		}
+	})
+}
+
+type failingStreamHandler struct {
+	err error
+}
+
+func (h *failingStreamHandler) Recv(msg proto.Message) error {
+	return h.err
+}
+
+func (h *failingStreamHandler) Send(msg proto.Message, opts ...psrpc.StreamOption) error {
+	return h.err
+}
+
+func (h *failingStreamHandler) Close(cause error) error {
+	return nil
+}
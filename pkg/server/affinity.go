@@ -0,0 +1,98 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/metadata"
+)
+
+// ClaimFunc is an affinity function which also returns metadata for the server's claim,
+// such as its region, version or remaining capacity. Clients can read it from psrpc.Claim.
+type ClaimFunc[RequestType proto.Message] func(context.Context, RequestType) (float32, metadata.Metadata)
+
+// StreamClaimFunc is the stream equivalent of ClaimFunc
+type StreamClaimFunc func(context.Context) (float32, metadata.Metadata)
+
+// NewClaimAffinityFunc adapts a ClaimFunc for use with RegisterHandler
+func NewClaimAffinityFunc[RequestType proto.Message](claimFunc ClaimFunc[RequestType]) AffinityFunc[RequestType] {
+	return func(ctx context.Context, req RequestType) float32 {
+		affinity, md := claimFunc(ctx, req)
+		metadata.SetClaimMetadata(ctx, md)
+		return affinity
+	}
+}
+
+// NewStreamClaimAffinityFunc adapts a StreamClaimFunc for use with RegisterStreamHandler
+func NewStreamClaimAffinityFunc(claimFunc StreamClaimFunc) StreamAffinityFunc {
+	return func(ctx context.Context) float32 {
+		affinity, md := claimFunc(ctx)
+		metadata.SetClaimMetadata(ctx, md)
+		return affinity
+	}
+}
+
+func newLoadAffinityFunc[RequestType proto.Message](
+	o psrpc.LoadAffinityOpts,
+	load func() int,
+	affinityFunc AffinityFunc[RequestType],
+) AffinityFunc[RequestType] {
+	return func(ctx context.Context, req RequestType) float32 {
+		if affinityFunc == nil {
+			return getLoadAffinity(o, load(), nil)
+		}
+		return getLoadAffinity(o, load(), func() float32 { return affinityFunc(ctx, req) })
+	}
+}
+
+func newStreamLoadAffinityFunc(
+	o psrpc.LoadAffinityOpts,
+	load func() int,
+	affinityFunc StreamAffinityFunc,
+) StreamAffinityFunc {
+	return func(ctx context.Context) float32 {
+		if affinityFunc == nil {
+			return getLoadAffinity(o, load(), nil)
+		}
+		return getLoadAffinity(o, load(), func() float32 { return affinityFunc(ctx) })
+	}
+}
+
+// getLoadAffinity scores a handler by its remaining capacity. handlers at capacity decline the request, as
+// does a negative affinity from the handler's own function.
+func getLoadAffinity(o psrpc.LoadAffinityOpts, load int, affinityFunc func() float32) float32 {
+	if load >= o.Capacity {
+		return -1
+	}
+	affinity := 1 - float32(load)/float32(o.Capacity)
+	if affinityFunc == nil {
+		return affinity
+	}
+
+	a := affinityFunc()
+	if a < 0 {
+		return a
+	}
+
+	weight := o.Weight
+	if weight <= 0 {
+		weight = psrpc.DefaultLoadAffinityWeight
+	}
+	return weight*affinity + (1-weight)*a
+}
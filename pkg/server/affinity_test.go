@@ -0,0 +1,40 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/psrpc"
+)
+
+func TestLoadAffinity(t *testing.T) {
+	o := psrpc.LoadAffinityOpts{Capacity: 4}
+
+	require.InDelta(t, 1, getLoadAffinity(o, 0, nil), 0.001)
+	require.InDelta(t, 0.25, getLoadAffinity(o, 3, nil), 0.001)
+	require.Equal(t, float32(-1), getLoadAffinity(o, 4, nil))
+	require.Equal(t, float32(-1), getLoadAffinity(o, 6, nil))
+	require.Equal(t, float32(-1), getLoadAffinity(o, 4, func() float32 { return 1 }))
+
+	require.InDelta(t, 0.5, getLoadAffinity(o, 2, func() float32 { return 0.5 }), 0.001)
+	require.InDelta(t, 0.75, getLoadAffinity(o, 0, func() float32 { return 0.5 }), 0.001)
+	require.Equal(t, float32(-1), getLoadAffinity(o, 0, func() float32 { return -1 }))
+
+	o.Weight = 1
+	require.InDelta(t, 0.25, getLoadAffinity(o, 3, func() float32 { return 1 }), 0.001)
+}
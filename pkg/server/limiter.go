@@ -0,0 +1,207 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gammazero/deque"
+
+	"github.com/livekit/psrpc"
+)
+
+type limiter struct {
+	limit        int
+	queueSize    int
+	queueTimeout time.Duration
+
+	mu     sync.Mutex
+	active int
+	queue  deque.Deque[chan struct{}]
+}
+
+func newLimiter(limit, queueSize int, queueTimeout time.Duration) *limiter {
+	return &limiter{
+		limit:        limit,
+		queueSize:    queueSize,
+		queueTimeout: queueTimeout,
+	}
+}
+
+// reserve takes a free slot, or a place in the queue if all slots are in use.
+// the returned channel is nil when a slot was acquired immediately.
+func (l *limiter) reserve() (chan struct{}, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.active < l.limit {
+		l.active++
+		return nil, true
+	}
+	if l.queue.Len() < l.queueSize {
+		ready := make(chan struct{})
+		l.queue.PushBack(ready)
+		return ready, true
+	}
+	return nil, false
+}
+
+// wait blocks until a queued reservation is handed a slot
+func (l *limiter) wait(ctx context.Context, ready chan struct{}) error {
+	if ready == nil {
+		return nil
+	}
+
+	var timeout <-chan time.Time
+	if l.queueTimeout > 0 {
+		timer := time.NewTimer(l.queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-ready:
+		return nil
+	case <-timeout:
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-ready:
+		return nil
+	default:
+		l.dequeue(ready)
+		return psrpc.ErrServerOverloaded
+	}
+}
+
+// cancel gives up a reservation, whether or not it was handed a slot
+func (l *limiter) cancel(ready chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if ready != nil {
+		select {
+		case <-ready:
+		default:
+			l.dequeue(ready)
+			return
+		}
+	}
+	l.releaseLocked()
+}
+
+func (l *limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.releaseLocked()
+}
+
+func (l *limiter) releaseLocked() {
+	if l.queue.Len() > 0 {
+		close(l.queue.PopFront())
+	} else {
+		l.active--
+	}
+}
+
+func (l *limiter) dequeue(ready chan struct{}) {
+	if i := l.queue.Index(func(c chan struct{}) bool { return c == ready }); i >= 0 {
+		l.queue.Remove(i)
+	}
+}
+
+// saturated is true when new requests can neither be handled nor queued
+func (l *limiter) saturated() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.active >= l.limit && l.queue.Len() >= l.queueSize
+}
+
+type reservation struct {
+	l     *limiter
+	ready chan struct{}
+}
+
+// limiters are acquired in order, so a handler limit is always taken before the server limit. each limiter is
+// only reserved once the previous one granted a slot, so requests queued on a busy handler don't hold server slots.
+type limiters []*limiter
+
+// reserve takes a slot or a place in the queue on the first limiter. requests are rejected up front if a later
+// limiter is saturated.
+func (ls limiters) reserve() ([]reservation, bool) {
+	if len(ls) == 0 {
+		return nil, true
+	}
+	for _, l := range ls[1:] {
+		if l.saturated() {
+			return nil, false
+		}
+	}
+
+	ready, ok := ls[0].reserve()
+	if !ok {
+		return nil, false
+	}
+	return []reservation{{ls[0], ready}}, true
+}
+
+// wait blocks until every limiter has granted a slot, reserving the later limiters as the earlier ones are
+// granted. it returns the reservations to release once the request is done.
+func (ls limiters) wait(ctx context.Context, rs []reservation) ([]reservation, error) {
+	for i, l := range ls {
+		if i == len(rs) {
+			ready, ok := l.reserve()
+			if !ok {
+				ls.release(rs)
+				return nil, psrpc.ErrServerOverloaded
+			}
+			rs = append(rs, reservation{l, ready})
+		}
+
+		if err := l.wait(ctx, rs[i].ready); err != nil {
+			ls.release(rs[:i])
+			return nil, err
+		}
+	}
+	return rs, nil
+}
+
+// cancel gives up reservations which were never waited on
+func (ls limiters) cancel(rs []reservation) {
+	for _, r := range rs {
+		r.l.cancel(r.ready)
+	}
+}
+
+// release frees the slots in reverse order, so a request handed a handler slot finds the server slot free
+func (ls limiters) release(rs []reservation) {
+	for i := len(rs) - 1; i >= 0; i-- {
+		rs[i].l.release()
+	}
+}
+
+func (ls limiters) saturated() bool {
+	for _, l := range ls {
+		if l.saturated() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/psrpc"
+)
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	ls := limiters{newLimiter(1, 1, 100*time.Millisecond)}
+
+	first, ok := ls.reserve()
+	require.True(t, ok)
+	first, err := ls.wait(ctx, first)
+	require.NoError(t, err)
+	require.False(t, ls.saturated())
+
+	queued, ok := ls.reserve()
+	require.True(t, ok)
+	require.True(t, ls.saturated())
+
+	_, ok = ls.reserve()
+	require.False(t, ok)
+
+	t.Run("queued request is handed the released slot", func(t *testing.T) {
+		done := make(chan error, 1)
+		go func() {
+			var err error
+			queued, err = ls.wait(ctx, queued)
+			done <- err
+		}()
+		ls.release(first)
+		require.NoError(t, <-done)
+		require.False(t, ls.saturated())
+	})
+
+	t.Run("queued request times out", func(t *testing.T) {
+		rs, ok := ls.reserve()
+		require.True(t, ok)
+		_, err := ls.wait(ctx, rs)
+		require.ErrorIs(t, err, psrpc.ErrServerOverloaded)
+		require.False(t, ls.saturated())
+
+		ls.release(queued)
+		rs, ok = ls.reserve()
+		require.True(t, ok)
+		_, err = ls.wait(ctx, rs)
+		require.NoError(t, err)
+	})
+}
+
+func TestLimitersQueuedHandler(t *testing.T) {
+	ctx := context.Background()
+	server := newLimiter(2, 0, 0)
+	busy := limiters{newLimiter(1, 1, time.Second), server}
+	idle := limiters{newLimiter(1, 1, time.Second), server}
+
+	first, ok := busy.reserve()
+	require.True(t, ok)
+	first, err := busy.wait(ctx, first)
+	require.NoError(t, err)
+
+	// a request queued on the busy handler doesn't take a server slot
+	queued, ok := busy.reserve()
+	require.True(t, ok)
+	require.True(t, busy.saturated())
+
+	rs, ok := idle.reserve()
+	require.True(t, ok)
+	rs, err = idle.wait(ctx, rs)
+	require.NoError(t, err)
+	require.True(t, server.saturated())
+
+	// the queued request takes the server slot once its handler slot is granted
+	done := make(chan error, 1)
+	go func() {
+		var err error
+		queued, err = busy.wait(ctx, queued)
+		done <- err
+	}()
+	idle.release(rs)
+	busy.release(first)
+	require.NoError(t, <-done)
+	require.Len(t, queued, 2)
+	busy.release(queued)
+	require.False(t, server.saturated())
+}
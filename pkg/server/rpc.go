@@ -20,6 +20,7 @@ import (
 	"sync"
 	"time"
 
+	"go.uber.org/atomic"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
@@ -32,6 +33,9 @@ import (
 	"github.com/livekit/psrpc/pkg/metadata"
 )
 
+// claimResponseGrace is how long a claim waits for the client's selection after its selection window ends
+const claimResponseGrace = 500 * time.Millisecond
+
 type AffinityFunc[RequestType proto.Message] func(context.Context, RequestType) float32
 
 type rpcHandlerImpl[RequestType proto.Message, ResponseType proto.Message] struct {
@@ -39,12 +43,15 @@ type rpcHandlerImpl[RequestType proto.Message, ResponseType proto.Message] struc
 
 	handler      func(context.Context, RequestType) (ResponseType, error)
 	affinityFunc AffinityFunc[RequestType]
+	limiters     limiters
 
 	mu          sync.RWMutex
 	requestSub  bus.Subscription[*internal.Request]
+	directSub   bus.Subscription[*internal.Request]
 	claimSub    bus.Subscription[*internal.ClaimResponse]
 	claims      map[string]chan *internal.ClaimResponse
 	handling    sync.WaitGroup
+	inflight    atomic.Int32
 	closeOnce   sync.Once
 	complete    chan struct{}
 	onCompleted func()
@@ -61,43 +68,60 @@ func newRPCHandler[RequestType proto.Message, ResponseType proto.Message](
 	ctx := context.Background()
 
 	var requestSub bus.Subscription[*internal.Request]
-	var claimSub bus.Subscription[*internal.ClaimResponse]
 	var err error
 
 	if i.Queue {
-		requestSub, err = bus.SubscribeQueue[*internal.Request](
-			ctx, s.bus, i.GetRPCChannel(), s.ChannelSize,
+		requestSub, err = bus.SubscribeQueueResilient[*internal.Request](
+			ctx, s.bus, i.GetRPCChannel(), s.ChannelSize, s.SubscriptionState,
 		)
 	} else {
-		requestSub, err = bus.Subscribe[*internal.Request](
-			ctx, s.bus, i.GetRPCChannel(), s.ChannelSize,
+		requestSub, err = bus.SubscribeResilient[*internal.Request](
+			ctx, s.bus, i.GetRPCChannel(), s.ChannelSize, s.SubscriptionState,
 		)
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	if i.RequireClaim {
-		claimSub, err = bus.Subscribe[*internal.ClaimResponse](
-			ctx, s.bus, i.GetClaimResponseChannel(), s.ChannelSize,
-		)
-		if err != nil {
-			_ = requestSub.Close()
-			return nil, err
-		}
-	} else {
-		claimSub = bus.EmptySubscription[*internal.ClaimResponse]{}
+	directSub, err := bus.SubscribeResilient[*internal.Request](
+		ctx, s.bus, i.GetServerRPCChannel(s.ID), s.ChannelSize, s.SubscriptionState,
+	)
+	if err != nil {
+		_ = requestSub.Close()
+		return nil, err
+	}
+
+	// direct requests are confirmed through claim responses, so every handler needs the subscription
+	claimSub, err := bus.SubscribeResilient[*internal.ClaimResponse](
+		ctx, s.bus, i.GetClaimResponseChannel(), s.ChannelSize, s.SubscriptionState,
+	)
+	if err != nil {
+		_ = requestSub.Close()
+		_ = directSub.Close()
+		return nil, err
 	}
 
 	h := &rpcHandlerImpl[RequestType, ResponseType]{
 		i:            i,
 		requestSub:   requestSub,
+		directSub:    directSub,
 		claimSub:     claimSub,
 		claims:       make(map[string]chan *internal.ClaimResponse),
 		affinityFunc: affinityFunc,
 		complete:     make(chan struct{}),
 	}
 
+	if s.LoadAffinity.Capacity > 0 {
+		h.affinityFunc = newLoadAffinityFunc(s.LoadAffinity, h.load, affinityFunc)
+	}
+
+	if s.HandlerConcurrency > 0 {
+		h.limiters = append(h.limiters, newLimiter(s.HandlerConcurrency, s.QueueSize, s.QueueTimeout))
+	}
+	if s.limiter != nil {
+		h.limiters = append(h.limiters, s.limiter)
+	}
+
 	if interceptor == nil {
 		h.handler = svcImpl
 	} else {
@@ -119,6 +143,7 @@ func newRPCHandler[RequestType proto.Message, ResponseType proto.Message](
 func (h *rpcHandlerImpl[RequestType, ResponseType]) run(s *RPCServer) {
 	go func() {
 		requests := h.requestSub.Channel()
+		directRequests := h.directSub.Channel()
 		claims := h.claimSub.Channel()
 
 		for {
@@ -130,13 +155,13 @@ func (h *rpcHandlerImpl[RequestType, ResponseType]) run(s *RPCServer) {
 				if ir == nil {
 					continue
 				}
-				if time.Now().UnixNano() < ir.Expiry {
-					go func() {
-						if err := h.handleRequest(s, ir); err != nil {
-							logger.Error(err, "failed to handle request", "requestID", ir.RequestId)
-						}
-					}()
+				h.dispatchRequest(s, ir, false)
+
+			case ir := <-directRequests:
+				if ir == nil {
+					continue
 				}
+				h.dispatchRequest(s, ir, true)
 
 			case claim := <-claims:
 				if claim == nil {
@@ -153,17 +178,46 @@ func (h *rpcHandlerImpl[RequestType, ResponseType]) run(s *RPCServer) {
 	}()
 }
 
+func (h *rpcHandlerImpl[RequestType, ResponseType]) dispatchRequest(
+	s *RPCServer,
+	ir *internal.Request,
+	direct bool,
+) {
+	if time.Now().UnixNano() >= ir.Expiry {
+		return
+	}
+
+	rs, ok := h.limiters.reserve()
+	if !ok {
+		if err := h.rejectRequest(s, ir, direct); err != nil {
+			logger.Error(err, "failed to reject request", "requestID", ir.RequestId)
+		}
+		return
+	}
+
+	go func() {
+		if err := h.handleRequest(s, ir, rs, direct); err != nil {
+			logger.Error(err, "failed to handle request", "requestID", ir.RequestId)
+		}
+	}()
+}
+
 func (h *rpcHandlerImpl[RequestType, ResponseType]) handleRequest(
 	s *RPCServer,
 	ir *internal.Request,
+	rs []reservation,
+	direct bool,
 ) error {
 	h.handling.Add(1)
 	defer h.handling.Done()
 
+	md, values := decodeMetadata(ir.Metadata, ir.MetadataValues)
 	head := &metadata.Header{
 		RemoteID: ir.ClientId,
 		SentAt:   time.Unix(0, ir.SentAt),
-		Metadata: ir.Metadata,
+		Metadata: md,
+		Values:   values,
+		Route:    ir.Route,
 	}
 	ctx := metadata.NewContextWithIncomingHeader(context.Background(), head)
 	ctx, cancel := context.WithDeadline(ctx, time.Unix(0, ir.Expiry))
@@ -171,14 +225,41 @@ func (h *rpcHandlerImpl[RequestType, ResponseType]) handleRequest(
 
 	req, err := bus.DeserializePayload[RequestType](ir.RawRequest)
 	if err != nil {
+		h.limiters.cancel(rs)
 		var res ResponseType
 		err = psrpc.NewError(psrpc.MalformedRequest, err)
 		_ = h.sendResponse(s, ctx, ir, res, err)
 		return err
 	}
 
-	if h.i.RequireClaim {
-		claimed, err := h.claimRequest(s, ctx, ir, req)
+	// direct requests are accepted before waiting for a slot. the client gives up on servers which don't
+	// answer within the affinity timeout, and would send the request to another server, so the handler
+	// only runs once the client confirms the accept.
+	if direct {
+		accepted, err := h.claimRequest(s, ctx, ir, req, true)
+		if err != nil || !accepted {
+			h.limiters.cancel(rs)
+			return err
+		}
+	}
+
+	// wait for a free slot before claiming, so queued servers are less likely to win
+	rs, err = h.limiters.wait(ctx, rs)
+	if err != nil {
+		if direct {
+			var res ResponseType
+			return h.sendResponse(s, ctx, ir, res, psrpc.ErrServerOverloaded)
+		}
+		return h.rejectRequest(s, ir, direct)
+	}
+	defer h.limiters.release(rs)
+
+	if !direct && h.i.RequireClaim {
+		// the client has already selected another server if its selection window passed while queued
+		if ir.ClaimExpiry != 0 && time.Now().UnixNano() > ir.ClaimExpiry {
+			return nil
+		}
+		claimed, err := h.claimRequest(s, ctx, ir, req, false)
 		if err != nil {
 			return err
 		} else if !claimed {
@@ -186,26 +267,154 @@ func (h *rpcHandlerImpl[RequestType, ResponseType]) handleRequest(
 		}
 	}
 
+	var idempotencyKey string
+	if ir.IdempotencyKey != "" && s.Idempotency.Cache != nil {
+		idempotencyKey = h.i.GetHandlerKey() + "|" + ir.IdempotencyKey
+		if done, err := h.sendStoredResponse(s, ctx, ir, idempotencyKey); done {
+			return err
+		}
+	}
+
 	// call handler function and return response
+	ctx, _ = metadata.NewContextWithResponseMetadata(ctx)
+	h.inflight.Inc()
+	defer h.inflight.Dec()
 	response, err := h.handler(ctx, req)
-	return h.sendResponse(s, ctx, ir, response, err)
+
+	res := h.newResponse(s, ctx, ir, response, err)
+	if idempotencyKey != "" {
+		h.storeResponse(s, ctx, idempotencyKey, res)
+	}
+	return s.bus.Publish(ctx, info.GetResponseChannel(s.Name, ir.ClientId), res)
 }
 
+// sendStoredResponse answers a duplicate request with the response to the first request with the same key,
+// waiting for it if the first request is still being handled. If the key is new, or the cache fails, the
+// request is handled normally. If the first request fails or its key expires, one waiting duplicate reserves
+// the key and is handled in its place.
+func (h *rpcHandlerImpl[RequestType, ResponseType]) sendStoredResponse(
+	s *RPCServer,
+	ctx context.Context,
+	ir *internal.Request,
+	key string,
+) (bool, error) {
+	var b []byte
+	for {
+		reserved, err := s.Idempotency.Cache.Reserve(ctx, key, s.Idempotency.TTL)
+		if err != nil {
+			logger.Error(err, "failed to reserve idempotency key", "requestID", ir.RequestId)
+			return false, nil
+		} else if reserved {
+			return false, nil
+		}
+
+		if b, err = s.Idempotency.Cache.Load(ctx, key); err == nil {
+			break
+		} else if ctx.Err() != nil {
+			return true, nil
+		}
+		// the key expired or its execution failed without a response, so try to reserve it again
+	}
+
+	res := &internal.Response{}
+	if err := proto.Unmarshal(b, res); err != nil {
+		logger.Error(err, "failed to decode stored response", "requestID", ir.RequestId)
+		return false, nil
+	}
+	res.RequestId = ir.RequestId
+	res.ServerId = s.ID
+	res.SentAt = time.Now().UnixNano()
+	return true, s.bus.Publish(ctx, info.GetResponseChannel(s.Name, ir.ClientId), res)
+}
+
+func (h *rpcHandlerImpl[RequestType, ResponseType]) storeResponse(
+	s *RPCServer,
+	ctx context.Context,
+	key string,
+	res *internal.Response,
+) {
+	var err error
+	if res.Code != "" {
+		err = s.Idempotency.Cache.Release(ctx, key)
+	} else {
+		var b []byte
+		if b, err = proto.Marshal(res); err == nil {
+			err = s.Idempotency.Cache.Store(ctx, key, b, s.Idempotency.TTL)
+		}
+	}
+	if err != nil {
+		logger.Error(err, "failed to store idempotent response", "requestID", res.RequestId)
+	}
+}
+
+func (h *rpcHandlerImpl[RequestType, ResponseType]) load() int {
+	return int(h.inflight.Load())
+}
+
+// rejectRequest sheds a request which cannot be handled or queued. requests requiring a claim are
+// dropped without claiming, leaving them to other servers.
+func (h *rpcHandlerImpl[RequestType, ResponseType]) rejectRequest(
+	s *RPCServer,
+	ir *internal.Request,
+	direct bool,
+) error {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Unix(0, ir.Expiry))
+	defer cancel()
+
+	switch {
+	case direct:
+		return h.publishClaim(s, ctx, ir, -1, nil)
+	case h.i.RequireClaim:
+		return nil
+	default:
+		var res ResponseType
+		return h.sendResponse(s, ctx, ir, res, psrpc.ErrServerOverloaded)
+	}
+}
+
+func (h *rpcHandlerImpl[RequestType, ResponseType]) getAffinity(
+	ctx context.Context,
+	req RequestType,
+) (float32, metadata.Metadata) {
+	if h.affinityFunc == nil {
+		return 1, nil
+	}
+	ctx, md := metadata.NewContextWithClaimMetadata(ctx)
+	affinity := h.affinityFunc(ctx, req)
+	return affinity, *md
+}
+
+func (h *rpcHandlerImpl[RequestType, ResponseType]) publishClaim(
+	s *RPCServer,
+	ctx context.Context,
+	ir *internal.Request,
+	affinity float32,
+	md metadata.Metadata,
+) error {
+	return s.bus.Publish(ctx, info.GetClaimRequestChannel(s.Name, ir.ClientId), &internal.ClaimRequest{
+		RequestId: ir.RequestId,
+		ServerId:  s.ID,
+		Affinity:  affinity,
+		Metadata:  md,
+	})
+}
+
+// claimRequest offers to handle a request and waits for the client to select this server. for direct
+// requests the claim acts as an accept, and a negative affinity is sent to the client as a rejection.
 func (h *rpcHandlerImpl[RequestType, ResponseType]) claimRequest(
 	s *RPCServer,
 	ctx context.Context,
 	ir *internal.Request,
 	req RequestType,
+	direct bool,
 ) (bool, error) {
 
-	var affinity float32
-	if h.affinityFunc != nil {
-		affinity = h.affinityFunc(ctx, req)
-		if affinity < 0 {
-			return false, nil
+	affinity, md := h.getAffinity(ctx, req)
+	if affinity < 0 {
+		if direct {
+			return false, h.publishClaim(s, ctx, ir, affinity, md)
 		}
-	} else {
-		affinity = 1
+		return false, nil
 	}
 
 	claimResponseChan := make(chan *internal.ClaimResponse, 1)
@@ -220,16 +429,16 @@ func (h *rpcHandlerImpl[RequestType, ResponseType]) claimRequest(
 		h.mu.Unlock()
 	}()
 
-	err := s.bus.Publish(ctx, info.GetClaimRequestChannel(s.Name, ir.ClientId), &internal.ClaimRequest{
-		RequestId: ir.RequestId,
-		ServerId:  s.ID,
-		Affinity:  affinity,
-	})
-	if err != nil {
+	if err := h.publishClaim(s, ctx, ir, affinity, md); err != nil {
 		return false, err
 	}
 
-	timeout := time.NewTimer(time.Duration(ir.Expiry - time.Now().UnixNano()))
+	// wait for the client's selection, allowing for the claim response to arrive after its selection window
+	expiry := ir.Expiry
+	if ir.ClaimExpiry != 0 {
+		expiry = min(expiry, ir.ClaimExpiry+int64(claimResponseGrace))
+	}
+	timeout := time.NewTimer(time.Duration(expiry - time.Now().UnixNano()))
 	defer timeout.Stop()
 
 	select {
@@ -252,11 +461,26 @@ func (h *rpcHandlerImpl[RequestType, ResponseType]) sendResponse(
 	response proto.Message,
 	err error,
 ) error {
+	res := h.newResponse(s, ctx, ir, response, err)
+	return s.bus.Publish(ctx, info.GetResponseChannel(s.Name, ir.ClientId), res)
+}
+
+func (h *rpcHandlerImpl[RequestType, ResponseType]) newResponse(
+	s *RPCServer,
+	ctx context.Context,
+	ir *internal.Request,
+	response proto.Message,
+	err error,
+) *internal.Response {
 	res := &internal.Response{
 		RequestId: ir.RequestId,
 		ServerId:  s.ID,
 		SentAt:    time.Now().UnixNano(),
 	}
+	if md := metadata.ResponseMetadataFromContext(ctx); md != nil {
+		res.Header, res.HeaderValues = internal.EncodeMetadata(md.Header)
+		res.Trailer, res.TrailerValues = internal.EncodeMetadata(md.Trailer)
+	}
 
 	if err != nil {
 		var e psrpc.Error
@@ -282,13 +506,13 @@ func (h *rpcHandlerImpl[RequestType, ResponseType]) sendResponse(
 			res.RawResponse = b
 		}
 	}
-
-	return s.bus.Publish(ctx, info.GetResponseChannel(s.Name, ir.ClientId), res)
+	return res
 }
 
 func (h *rpcHandlerImpl[RequestType, ResponseType]) close(force bool) {
 	h.closeOnce.Do(func() {
 		_ = h.requestSub.Close()
+		_ = h.directSub.Close()
 		if !force {
 			h.handling.Wait()
 		}
@@ -24,8 +24,10 @@ import (
 	"google.golang.org/protobuf/proto"
 
 	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/internal"
 	"github.com/livekit/psrpc/internal/bus"
 	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/metadata"
 )
 
 type rpcHandler interface {
@@ -36,7 +38,8 @@ type RPCServer struct {
 	*info.ServiceDefinition
 	psrpc.ServerOpts
 
-	bus bus.MessageBus
+	bus     bus.MessageBus
+	limiter *limiter
 
 	mu       sync.RWMutex
 	handlers map[string]rpcHandler
@@ -54,6 +57,9 @@ func NewRPCServer(sd *info.ServiceDefinition, b bus.MessageBus, opts ...psrpc.Se
 	if s.ServerID != "" {
 		s.ID = s.ServerID
 	}
+	if s.MaxConcurrency > 0 {
+		s.limiter = newLimiter(s.MaxConcurrency, s.QueueSize, s.QueueTimeout)
+	}
 
 	return s
 }
@@ -144,6 +150,51 @@ func RegisterStreamHandler[RequestType proto.Message, ResponseType proto.Message
 	return nil
 }
 
+// RegisterServerStreamHandler registers a server streaming RPC. Each request opens a stream, which the
+// handler can send responses on until it returns.
+func RegisterServerStreamHandler[RequestType proto.Message, ResponseType proto.Message](
+	s *RPCServer,
+	rpc string,
+	topic []string,
+	svcImpl func(RequestType, psrpc.SendStream[ResponseType]) error,
+	affinityFunc AffinityFunc[RequestType],
+) error {
+	if s.shutdown.IsBroken() {
+		return psrpc.ErrServerClosed
+	}
+
+	i := s.GetInfo(rpc, topic)
+
+	key := i.GetHandlerKey()
+	s.mu.RLock()
+	_, ok := s.handlers[key]
+	s.mu.RUnlock()
+	if ok {
+		return errors.New("handler already exists")
+	}
+
+	// create handler
+	h, err := newServerStreamRPCHandler(s, i, svcImpl, affinityFunc)
+	if err != nil {
+		return err
+	}
+
+	s.active.Add(1)
+	h.onCompleted = func() {
+		s.active.Done()
+		s.mu.Lock()
+		delete(s.handlers, key)
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	s.handlers[key] = h
+	s.mu.Unlock()
+
+	h.run(s)
+	return nil
+}
+
 func (s *RPCServer) DeregisterHandler(rpc string, topic []string) {
 	i := s.GetInfo(rpc, topic)
 	key := i.GetHandlerKey()
@@ -182,3 +233,12 @@ func (s *RPCServer) Close(force bool) {
 		s.active.Wait()
 	}
 }
+
+// decodeMetadata combines the string and multi-valued metadata sent with a request
+func decodeMetadata(strs map[string]string, values map[string]*internal.MetadataValues) (metadata.Metadata, metadata.MD) {
+	md := internal.DecodeMetadata(strs, values)
+	if len(values) == 0 {
+		return strs, md
+	}
+	return md.Strings(), md
+}
@@ -41,6 +41,10 @@ type streamHandler[RecvType, SendType proto.Message] struct {
 	interceptors []psrpc.StreamInterceptor
 	affinityFunc StreamAffinityFunc
 
+	// server streaming RPCs receive their request with the open message
+	requestHandler      func(RecvType, psrpc.SendStream[SendType]) error
+	requestAffinityFunc AffinityFunc[RecvType]
+
 	mu          sync.RWMutex
 	streamSub   bus.Subscription[*internal.Stream]
 	claimSub    bus.Subscription[*internal.ClaimResponse]
@@ -58,10 +62,48 @@ func newStreamRPCHandler[RecvType, SendType proto.Message](
 	svcImpl func(psrpc.ServerStream[SendType, RecvType]) error,
 	affinityFunc StreamAffinityFunc,
 ) (*streamHandler[RecvType, SendType], error) {
+	h, err := newStreamHandler[RecvType, SendType](s, i)
+	if err != nil {
+		return nil, err
+	}
+
+	h.handler = svcImpl
+	h.affinityFunc = affinityFunc
+	if s.LoadAffinity.Capacity > 0 {
+		h.affinityFunc = newStreamLoadAffinityFunc(s.LoadAffinity, h.load, affinityFunc)
+	}
+
+	return h, nil
+}
+
+func newServerStreamRPCHandler[RecvType, SendType proto.Message](
+	s *RPCServer,
+	i *info.RequestInfo,
+	svcImpl func(RecvType, psrpc.SendStream[SendType]) error,
+	affinityFunc AffinityFunc[RecvType],
+) (*streamHandler[RecvType, SendType], error) {
+	h, err := newStreamHandler[RecvType, SendType](s, i)
+	if err != nil {
+		return nil, err
+	}
+
+	h.requestHandler = svcImpl
+	h.requestAffinityFunc = affinityFunc
+	if s.LoadAffinity.Capacity > 0 {
+		h.requestAffinityFunc = newLoadAffinityFunc(s.LoadAffinity, h.load, affinityFunc)
+	}
+
+	return h, nil
+}
+
+func newStreamHandler[RecvType, SendType proto.Message](
+	s *RPCServer,
+	i *info.RequestInfo,
+) (*streamHandler[RecvType, SendType], error) {
 
 	ctx := context.Background()
-	streamSub, err := bus.Subscribe[*internal.Stream](
-		ctx, s.bus, i.GetStreamServerChannel(), s.ChannelSize,
+	streamSub, err := bus.SubscribeResilient[*internal.Stream](
+		ctx, s.bus, i.GetStreamServerChannel(), s.ChannelSize, s.SubscriptionState,
 	)
 	if err != nil {
 		return nil, err
@@ -69,8 +111,8 @@ func newStreamRPCHandler[RecvType, SendType proto.Message](
 
 	var claimSub bus.Subscription[*internal.ClaimResponse]
 	if i.RequireClaim {
-		claimSub, err = bus.Subscribe[*internal.ClaimResponse](
-			ctx, s.bus, i.GetClaimResponseChannel(), s.ChannelSize,
+		claimSub, err = bus.SubscribeResilient[*internal.ClaimResponse](
+			ctx, s.bus, i.GetClaimResponseChannel(), s.ChannelSize, s.SubscriptionState,
 		)
 		if err != nil {
 			_ = streamSub.Close()
@@ -80,17 +122,14 @@ func newStreamRPCHandler[RecvType, SendType proto.Message](
 		claimSub = bus.EmptySubscription[*internal.ClaimResponse]{}
 	}
 
-	h := &streamHandler[RecvType, SendType]{
-		i:            i,
-		streamSub:    streamSub,
-		claimSub:     claimSub,
-		streams:      make(map[string]stream.Stream[SendType, RecvType]),
-		claims:       make(map[string]chan *internal.ClaimResponse),
-		affinityFunc: affinityFunc,
-		handler:      svcImpl,
-		complete:     make(chan struct{}),
-	}
-	return h, nil
+	return &streamHandler[RecvType, SendType]{
+		i:         i,
+		streamSub: streamSub,
+		claimSub:  claimSub,
+		streams:   make(map[string]stream.Stream[SendType, RecvType]),
+		claims:    make(map[string]chan *internal.ClaimResponse),
+		complete:  make(chan struct{}),
+	}, nil
 }
 
 func (h *streamHandler[RecvType, SendType]) run(s *RPCServer) {
@@ -159,22 +198,44 @@ func (h *streamHandler[RecvType, SendType]) handleOpenRequest(
 	is *internal.Stream,
 	open *internal.StreamOpen,
 ) error {
+	md, values := decodeMetadata(open.Metadata, open.MetadataValues)
 	head := &metadata.Header{
 		RemoteID: open.NodeId,
 		SentAt:   time.Unix(0, is.SentAt),
-		Metadata: open.Metadata,
+		Metadata: md,
+		Values:   values,
+		Route:    open.Route,
 	}
 	ctx := metadata.NewContextWithIncomingHeader(context.Background(), head)
 	octx, cancel := context.WithDeadline(ctx, time.Unix(0, is.Expiry))
 	defer cancel()
 
+	var req RecvType
+	if h.requestHandler != nil {
+		var err error
+		req, err = bus.DeserializePayload[RecvType](open.RawRequest)
+		if err != nil {
+			return psrpc.NewError(psrpc.MalformedRequest, err)
+		}
+	}
+
 	if h.i.RequireClaim {
-		claimed, err := h.claimRequest(s, octx, is)
+		claimed, err := h.claimRequest(s, octx, is, req)
 		if !claimed {
 			return err
 		}
 	}
 
+	// restore application state before the handler sees replayed messages
+	var resumeErr error
+	if open.Resumed && s.StreamResumeHook != nil {
+		var rctx context.Context
+		if rctx, resumeErr = s.StreamResumeHook(ctx, h.i.RPCInfo, open.ResumeToken); resumeErr == nil {
+			ctx = rctx
+		}
+	}
+
+	ctx, resMD := metadata.NewContextWithResponseMetadata(ctx)
 	ss := stream.NewStream[SendType, RecvType](
 		ctx,
 		h.i,
@@ -188,18 +249,33 @@ func (h *streamHandler[RecvType, SendType]) handleOpenRequest(
 		s.StreamInterceptors,
 		make(chan RecvType, s.ChannelSize),
 		make(map[string]chan struct{}),
+		stream.WithRecvWindow(s.StreamWindow),
+		stream.WithSendWindow(int(open.Window)),
+		stream.WithKeepalive(s.StreamKeepalive),
+		stream.WithResumeToken(open.ResumeToken),
+		stream.WithResponseMetadata(resMD),
 	)
 
 	h.mu.Lock()
 	h.streams[is.StreamId] = ss
 	h.mu.Unlock()
 
+	if resumeErr != nil {
+		_ = ss.Close(resumeErr)
+		return resumeErr
+	}
+
 	if err := ss.Ack(octx, is); err != nil {
 		_ = ss.Close(err)
 		return err
 	}
 
-	err := h.handler(ss)
+	var err error
+	if h.requestHandler != nil {
+		err = h.requestHandler(req, ss)
+	} else {
+		err = h.handler(ss)
+	}
 	if !ss.Hijacked() {
 		_ = ss.Close(err)
 	}
@@ -211,16 +287,12 @@ func (h *streamHandler[RecvType, SendType]) claimRequest(
 	s *RPCServer,
 	ctx context.Context,
 	is *internal.Stream,
+	req RecvType,
 ) (bool, error) {
 
-	var affinity float32
-	if h.affinityFunc != nil {
-		affinity = h.affinityFunc(ctx)
-		if affinity < 0 {
-			return false, nil
-		}
-	} else {
-		affinity = 1
+	affinity, md := h.getAffinity(ctx, req)
+	if affinity < 0 {
+		return false, nil
 	}
 
 	claimResponseChan := make(chan *internal.ClaimResponse, 1)
@@ -239,6 +311,7 @@ func (h *streamHandler[RecvType, SendType]) claimRequest(
 		RequestId: is.RequestId,
 		ServerId:  s.ID,
 		Affinity:  affinity,
+		Metadata:  md,
 	})
 	if err != nil {
 		return false, err
@@ -260,6 +333,27 @@ func (h *streamHandler[RecvType, SendType]) claimRequest(
 	}
 }
 
+func (h *streamHandler[RecvType, SendType]) getAffinity(ctx context.Context, req RecvType) (float32, metadata.Metadata) {
+	switch {
+	case h.requestAffinityFunc != nil:
+		ctx, md := metadata.NewContextWithClaimMetadata(ctx)
+		affinity := h.requestAffinityFunc(ctx, req)
+		return affinity, *md
+	case h.affinityFunc != nil:
+		ctx, md := metadata.NewContextWithClaimMetadata(ctx)
+		affinity := h.affinityFunc(ctx)
+		return affinity, *md
+	default:
+		return 1, nil
+	}
+}
+
+func (h *streamHandler[RecvType, SendType]) load() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.streams)
+}
+
 func (h *streamHandler[RecvType, SendType]) close(force bool) {
 	h.closeOnce.Do(func() {
 		h.draining.Store(true)
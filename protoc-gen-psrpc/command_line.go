@@ -15,6 +15,7 @@ package main
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -23,6 +24,7 @@ type commandLineParams struct {
 	paths        string            // paths flag, used to control file output directory.
 	module       string            // module flag, Go import path prefix that is removed from the output filename.
 	importPrefix string            // prefix added to imported package file names.
+	multiHelpers bool              // multi_helpers flag, generates psrpc.MultiRequest constructors for multi-RPCs.
 }
 
 // parseCommandLineParams breaks the comma-separated list of key=value pairs
@@ -76,6 +78,14 @@ func parseCommandLineParams(parameter string) (*commandLineParams, error) {
 		case k == "import_prefix":
 			clp.importPrefix = v
 
+		// If multi_helpers=true, MultiRequest constructors are generated for multi-RPCs
+		case k == "multi_helpers":
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid command line flag %s=%s", k, v)
+			}
+			clp.multiHelpers = b
+
 		default:
 			return nil, fmt.Errorf("invalid command line flag %s=%s", k, v)
 		}
@@ -135,6 +135,21 @@ func TestParseCommandLineParams(t *testing.T) {
 			},
 			nil,
 		},
+		{
+			"multi_helpers parameter",
+			"multi_helpers=true",
+			&commandLineParams{
+				importMap:    map[string]string{},
+				multiHelpers: true,
+			},
+			nil,
+		},
+		{
+			"multi_helpers invalidstuff",
+			"multi_helpers=invalidstuff",
+			nil,
+			errors.New(`invalid command line flag multi_helpers=invalidstuff`),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -57,6 +57,9 @@ type psrpc struct {
 	sourceRelativePaths bool // instruction on where to write output files
 	modulePrefix        string
 
+	// Optional output:
+	multiHelpers bool // generate MultiRequest constructors for multi-RPCs
+
 	// Package naming:
 	genPkgName          string // Name of the package that we're generating
 	fileToGoPackageName map[*descriptor.FileDescriptorProto]string
@@ -91,6 +94,7 @@ func (t *psrpc) Generate(in *plugin.CodeGeneratorRequest) *plugin.CodeGeneratorR
 	t.importMap = params.importMap
 	t.sourceRelativePaths = params.paths == "source_relative"
 	t.modulePrefix = params.module
+	t.multiHelpers = params.multiHelpers
 
 	t.genFiles = gen.FilesToGenerate(in)
 
@@ -374,6 +378,10 @@ func (t *psrpc) generateService(file *descriptor.FileDescriptorProto, service *d
 	t.sectionComment(servName + ` Client`)
 	t.generateClient(service)
 
+	if t.multiHelpers {
+		t.generateMultiHelpers(service)
+	}
+
 	t.sectionComment(servName + ` Server`)
 	t.generateServer(service)
 }
@@ -424,6 +432,9 @@ func (t *psrpc) generateInterface(file *descriptor.FileDescriptorProto, service
 		t.P(`  // Close immediately, without waiting for pending RPCs`)
 		t.P(`  Kill()`)
 	} else if iface == client {
+		t.P(`  // Close after pending RPCs and streams complete, or once ctx is done`)
+		t.P(`  Shutdown(ctx `, t.pkgs["context"], `.Context) error`)
+		t.P()
 		t.P(`  // Close immediately, without waiting for pending RPCs`)
 		t.P(`  Close()`)
 	}
@@ -446,12 +457,28 @@ func (t *psrpc) generateClientSignature(method *descriptor.MethodDescriptorProto
 	}
 	if opts.Subscription {
 		t.P(`) (`, t.pkgs["psrpc"], `.Subscription[*`, outputType, `], error)`)
+		t.P()
+
+		t.W(`  Consume`, methName, `(ctx `, t.pkgs["context"], `.Context`)
+		if opts.Topics {
+			t.W(`, `, t.topicsForMethod(method).FormatParams())
+		}
+		t.P(`, handler func(`, t.pkgs["context"], `.Context, *`, outputType, `) error, opts ...`, t.pkgs["psrpc"], `.ConsumeOption) (`, t.pkgs["psrpc"], `.Consumer, error)`)
 	} else if opts.Stream {
 		t.P(`, opts ...`, t.pkgs["psrpc"], `.RequestOption) (`, t.pkgs["psrpc"], `.ClientStream[*`, inputType, `, *`, outputType, `], error)`)
+	} else if opts.ServerStream {
+		t.P(`, req *`, inputType, `, opts ...`, t.pkgs["psrpc"], `.RequestOption) (`, t.pkgs["psrpc"], `.ReceiveStream[*`, outputType, `], error)`)
 	} else if opts.Type == options.Routing_MULTI {
 		t.P(`, req *`, inputType, `, opts ...`, t.pkgs["psrpc"], `.RequestOption) (<-chan *`, t.pkgs["psrpc"], `.Response[*`, outputType, `], error)`)
 	} else {
 		t.P(`, req *`, inputType, `, opts ...`, t.pkgs["psrpc"], `.RequestOption) (*`, outputType, `, error)`)
+		t.P()
+
+		t.W(`  `, methName, `Async(ctx `, t.pkgs["context"], `.Context`)
+		if opts.Topics {
+			t.W(`, `, t.topicsForMethod(method).FormatParams())
+		}
+		t.P(`, req *`, inputType, `, opts ...`, t.pkgs["psrpc"], `.RequestOption) *`, t.pkgs["psrpc"], `.Future[*`, outputType, `]`)
 	}
 	t.P()
 }
@@ -489,7 +516,7 @@ func (t *psrpc) generateClient(service *descriptor.ServiceDescriptorProto) {
 
 	clientConstructor := `NewRPCClient`
 	for _, method := range service.Method {
-		if t.getOptions(method).Stream {
+		if opts := t.getOptions(method); opts.Stream || opts.ServerStream {
 			clientConstructor = `NewRPCClientWithStreams`
 		}
 	}
@@ -528,7 +555,9 @@ func (t *psrpc) generateClient(service *descriptor.ServiceDescriptorProto) {
 			t.P(`, opts ...`, t.pkgs["psrpc"], `.RequestOption) (`, t.pkgs["psrpc"], `.ClientStream[*`, inputType, `, *`, outputType, `], error) {`)
 		} else {
 			t.W(`, req *`, inputType, `, opts ...`, t.pkgs["psrpc"], `.RequestOption`)
-			if opts.Type == options.Routing_MULTI {
+			if opts.ServerStream {
+				t.P(`) (`, t.pkgs["psrpc"], `.ReceiveStream[*`, outputType, `], error) {`)
+			} else if opts.Type == options.Routing_MULTI {
 				t.P(`) (<-chan *`, t.pkgs["psrpc"], `.Response[*`, outputType, `], error) {`)
 			} else {
 				t.P(`) (*`, outputType, `, error) {`)
@@ -545,6 +574,8 @@ func (t *psrpc) generateClient(service *descriptor.ServiceDescriptorProto) {
 			t.P(outputType, `](ctx, c.client, "`, methName, `", `, topics.FormatCastToStringSlice(), `)`)
 		} else if opts.Stream {
 			t.P(`.OpenStream[*`, inputType, `, *`, outputType, `](ctx, c.client, "`, methName, `", `, topics.FormatCastToStringSlice(), `, opts...)`)
+		} else if opts.ServerStream {
+			t.P(`.OpenServerStream[*`, outputType, `](ctx, c.client, "`, methName, `", `, topics.FormatCastToStringSlice(), `, req, opts...)`)
 		} else {
 			if opts.Type == options.Routing_MULTI {
 				t.W(`.RequestMulti[*`)
@@ -556,14 +587,79 @@ func (t *psrpc) generateClient(service *descriptor.ServiceDescriptorProto) {
 		}
 		t.P(`}`)
 		t.P()
+
+		if opts.Subscription {
+			t.W(`func (c *`, structName, servTopics.FormatTypeParams(), `) Consume`, methName, `(ctx `, t.pkgs["context"], `.Context`)
+			if opts.Topics {
+				t.W(`, `, topics.FormatParams())
+			}
+			t.P(`, handler func(`, t.pkgs["context"], `.Context, *`, outputType, `) error, opts ...`, t.pkgs["psrpc"], `.ConsumeOption) (`, t.pkgs["psrpc"], `.Consumer, error) {`)
+			t.W(`  return `, t.pkgs["client"])
+			if opts.Type == options.Routing_MULTI {
+				t.W(`.Consume[*`)
+			} else {
+				t.W(`.ConsumeQueue[*`)
+			}
+			t.P(outputType, `](ctx, c.client, "`, methName, `", `, topics.FormatCastToStringSlice(), `, handler, opts...)`)
+			t.P(`}`)
+			t.P()
+		} else if !opts.Stream && !opts.ServerStream && opts.Type != options.Routing_MULTI {
+			t.W(`func (c *`, structName, servTopics.FormatTypeParams(), `) `, methName, `Async(ctx `, t.pkgs["context"], `.Context`)
+			if opts.Topics {
+				t.W(`, `, topics.FormatParams())
+			}
+			t.P(`, req *`, inputType, `, opts ...`, t.pkgs["psrpc"], `.RequestOption) *`, t.pkgs["psrpc"], `.Future[*`, outputType, `] {`)
+			t.P(`  return `, t.pkgs["client"], `.RequestSingleAsync[*`, outputType, `](ctx, c.client, "`, methName, `", `, topics.FormatCastToStringSlice(), `, req, opts...)`)
+			t.P(`}`)
+			t.P()
+		}
 	}
 
+	t.P(`func (s *`, structName, servTopics.FormatTypeParams(), `) Shutdown(ctx `, t.pkgs["context"], `.Context) error {`)
+	t.P(`  return s.client.Shutdown(ctx)`)
+	t.P(`}`)
+	t.P()
+
 	t.P(`func (s *`, structName, servTopics.FormatTypeParams(), `) Close() {`)
 	t.P(`  s.client.Close()`)
 	t.P(`}`)
 	t.P()
 }
 
+func (t *psrpc) generateMultiHelpers(service *descriptor.ServiceDescriptorProto) {
+	servName := serviceNameCamelCased(service)
+	servTopics := t.typedTopicsForService(service)
+
+	for _, method := range service.Method {
+		opts := t.getOptions(method)
+		if opts.Type != options.Routing_MULTI || opts.Subscription || opts.Stream || opts.ServerStream {
+			continue
+		}
+
+		methName := methodNameCamelCased(method)
+		inputType := t.goTypeName(method.GetInputType())
+		outputType := t.goTypeName(method.GetOutputType())
+		topics := t.topicsForMethod(method)
+		funcName := `New` + servName + methName + `MultiRequest`
+
+		t.P(`// `, funcName, ` returns a psrpc.MultiRequest for use with helpers such as psrpc.CollectAll.`)
+		t.W(`func `, funcName, servTopics.FormatTypeParamConstraints(), `(c `, servName, `Client`, servTopics.FormatTypeParams())
+		if opts.Topics {
+			t.W(`, `, topics.FormatParams())
+		}
+		t.P(`, req *`, inputType, `, opts ...`, t.pkgs["psrpc"], `.RequestOption) `, t.pkgs["psrpc"], `.MultiRequest[*`, outputType, `] {`)
+		t.P(`  return func(ctx `, t.pkgs["context"], `.Context) (<-chan *`, t.pkgs["psrpc"], `.Response[*`, outputType, `], error) {`)
+		t.W(`    return c.`, methName, `(ctx`)
+		if opts.Topics {
+			t.W(`, `, strings.Join(topics.VarNames(), `, `))
+		}
+		t.P(`, req, opts...)`)
+		t.P(`  }`)
+		t.P(`}`)
+		t.P()
+	}
+}
+
 func (t *psrpc) generateServerImplSignature(method *descriptor.MethodDescriptorProto, opts *options.Options) {
 	methName := methodNameCamelCased(method)
 	inputType := t.goTypeName(method.GetInputType())
@@ -574,6 +670,11 @@ func (t *psrpc) generateServerImplSignature(method *descriptor.MethodDescriptorP
 		if opts.Type == options.Routing_AFFINITY {
 			t.P(`  `, methName, `Affinity(context.Context) float32`)
 		}
+	} else if opts.ServerStream {
+		t.P(`  `, methName, `(*`, inputType, `, `, t.pkgs["psrpc"], `.SendStream[*`, outputType, `]) error`)
+		if opts.Type == options.Routing_AFFINITY {
+			t.P(`  `, methName, `Affinity(context.Context, *`, inputType, `) float32`)
+		}
 	} else {
 		t.P(`  `, methName, `(`, t.pkgs["context"], `.Context, *`, inputType, `) (*`, outputType, `, error)`)
 		if opts.Type == options.Routing_AFFINITY {
@@ -646,10 +747,7 @@ func (t *psrpc) generateServer(service *descriptor.ServiceDescriptorProto) {
 			errVar = true
 		}
 
-		registerFuncName := "RegisterHandler"
-		if opts.Stream {
-			registerFuncName = "RegisterStreamHandler"
-		}
+		registerFuncName := t.registerFuncName(opts)
 		t.W(`  err = `, t.pkgs["server"], `.`, registerFuncName, `(s, "`, methName, `", nil, svc.`, methName)
 		if t.getOptions(method).Type == options.Routing_AFFINITY {
 			t.W(`, svc.`, methName, `Affinity`)
@@ -691,10 +789,7 @@ func (t *psrpc) generateServer(service *descriptor.ServiceDescriptorProto) {
 			t.P(`}`)
 			t.P()
 		} else {
-			registerFuncName := "RegisterHandler"
-			if opts.Stream {
-				registerFuncName = "RegisterStreamHandler"
-			}
+			registerFuncName := t.registerFuncName(opts)
 			t.P(`func (s *`, servStruct, servTopics.FormatTypeParams(), `) Register`, methName, `Topic(`, topics.FormatParams(), `) error {`)
 			t.W(`  return `, t.pkgs["server"], `.`, registerFuncName, `(s.rpc, "`, methName, `", `, topics.FormatCastToStringSlice(), `, s.svc.`, methName)
 			if t.getOptions(method).Type == options.Routing_AFFINITY {
@@ -743,10 +838,15 @@ func (t *psrpc) generateServer(service *descriptor.ServiceDescriptorProto) {
 
 func (t *psrpc) getOptions(method *descriptor.MethodDescriptorProto) *options.Options {
 	if method.Options == nil || !proto.HasExtension(method.Options, options.E_Options) {
-		return &options.Options{}
+		return &options.Options{
+			ServerStream: method.GetServerStreaming() && !method.GetClientStreaming(),
+		}
 	}
 
 	opts := proto.GetExtension(method.Options, options.E_Options).(*options.Options)
+	if method.GetServerStreaming() && !method.GetClientStreaming() {
+		opts.ServerStream = true
+	}
 	switch opts.Routing.(type) {
 	case *options.Options_AffinityFunc:
 		opts.Type = options.Routing_AFFINITY
@@ -759,6 +859,17 @@ func (t *psrpc) getOptions(method *descriptor.MethodDescriptorProto) *options.Op
 	return opts
 }
 
+func (t *psrpc) registerFuncName(opts *options.Options) string {
+	switch {
+	case opts.Stream:
+		return "RegisterStreamHandler"
+	case opts.ServerStream:
+		return "RegisterServerStreamHandler"
+	default:
+		return "RegisterHandler"
+	}
+}
+
 func (t *psrpc) getRequireClaim(opts *options.Options) bool {
 	return opts.Type != options.Routing_MULTI && (opts.TopicParams == nil || !opts.TopicParams.SingleServer)
 }
@@ -18,28 +18,49 @@ import (
 	"time"
 
 	"golang.org/x/exp/slices"
+
+	"github.com/livekit/psrpc/pkg/metadata"
 )
 
 type RequestOption func(*RequestOpts)
 
 type RequestOpts struct {
-	Timeout       time.Duration
-	SelectionOpts SelectionOpts
-	Interceptors  []any
+	Timeout           time.Duration
+	SelectionOpts     SelectionOpts
+	RoutingKey        string
+	TargetServer      string
+	ExpectedResponses int
+	ExpectedServers   []string
+	StreamWindow      int
+	StreamKeepalive   KeepaliveOpts
+	StreamResumable   bool
+	ResumeToken       string
+	ResponseMetadata  *metadata.ResponseMetadata
+	IdempotencyKey    string
+	Interceptors      []any
 }
 
 type SelectionOpts struct {
-	MinimumAffinity      float32                        // minimum affinity for a server to be considered a valid handler
-	MaximumAffinity      float32                        // if > 0, any server returning a max score will be selected immediately
-	AcceptFirstAvailable bool                           // go fast
-	AffinityTimeout      time.Duration                  // server selection deadline
-	ShortCircuitTimeout  time.Duration                  // deadline imposed after receiving first response
-	SelectionFunc        func([]*Claim) (string, error) // custom server selection function
+	MinimumAffinity      float32            // minimum affinity for a server to be considered a valid handler
+	MaximumAffinity      float32            // if > 0, any server returning a max score will be selected immediately
+	AcceptFirstAvailable bool               // go fast
+	AffinityTimeout      time.Duration      // server selection deadline
+	ShortCircuitTimeout  time.Duration      // deadline imposed after receiving first response
+	SelectionFunc        SelectionFunc      // custom server selection function
+	KeyedSelectionFunc   KeyedSelectionFunc // custom server selection function using the request's routing key
 }
 
+// SelectionFunc chooses a server from the claims received before the selection deadline
+type SelectionFunc func([]*Claim) (string, error)
+
+// KeyedSelectionFunc chooses a server from the claims received before the selection deadline, using the
+// routing key set on the request with WithRoutingKey
+type KeyedSelectionFunc func(key string, claims []*Claim) (string, error)
+
 type Claim struct {
 	ServerID string
 	Affinity float32
+	Metadata map[string]string
 }
 
 func WithRequestTimeout(timeout time.Duration) RequestOption {
@@ -54,10 +75,89 @@ func WithSelectionOpts(opts SelectionOpts) RequestOption {
 	}
 }
 
+// WithRoutingKey sets the key used by clients with sticky routing to remember the selected server, and by
+// keyed selection functions such as ConsistentHashSelection
+func WithRoutingKey(key string) RequestOption {
+	return func(o *RequestOpts) {
+		o.RoutingKey = key
+	}
+}
+
+// WithTargetServer sends the request directly to the server with the given ID, skipping server selection.
+// Only single RPCs honor it; multi RPCs and streams fail with InvalidArgument when it is set.
+func WithTargetServer(id string) RequestOption {
+	return func(o *RequestOpts) {
+		o.TargetServer = id
+	}
+}
+
 type RequestInterceptor interface {
 	ClientRPCInterceptor | ClientMultiRPCInterceptor | StreamInterceptor
 }
 
+// WithExpectedResponses completes a multi-RPC as soon as count responses have been received
+func WithExpectedResponses(count int) RequestOption {
+	return func(o *RequestOpts) {
+		o.ExpectedResponses = count
+	}
+}
+
+// WithExpectedServers completes a multi-RPC as soon as each of the servers has responded.
+// If the request times out or its context is done first, the missing servers are reported in a MissingResponsesError.
+func WithExpectedServers(ids ...string) RequestOption {
+	return func(o *RequestOpts) {
+		o.ExpectedServers = ids
+	}
+}
+
+// WithStreamWindow enables credit based flow control on a stream. The server may send up to window
+// messages ahead of the client's consumer, and sends from each side are pipelined up to the peer's window.
+func WithStreamWindow(window int) RequestOption {
+	return func(o *RequestOpts) {
+		o.StreamWindow = window
+	}
+}
+
+// WithStreamKeepalive pings the server while the stream is open, closing it if the server stops responding
+func WithStreamKeepalive(opts KeepaliveOpts) RequestOption {
+	return func(o *RequestOpts) {
+		o.StreamKeepalive = opts
+	}
+}
+
+// WithResumableStream gives the stream a resume token, and keeps messages which have not been acknowledged
+// by the server so they can be replayed if the stream is resumed
+func WithResumableStream() RequestOption {
+	return func(o *RequestOpts) {
+		o.StreamResumable = true
+	}
+}
+
+// WithResume reopens a resumable stream which was lost, replaying its unacknowledged messages. The new stream
+// may be handled by a different server, which can restore the stream's state with a StreamResumeHook.
+func WithResume(token string) RequestOption {
+	return func(o *RequestOpts) {
+		o.StreamResumable = true
+		o.ResumeToken = token
+	}
+}
+
+// WithResponseMetadata fills md with the header and trailer set by the server. For streams, it is filled
+// when the server closes the stream. Multi-RPC responses carry their own metadata instead.
+func WithResponseMetadata(md *metadata.ResponseMetadata) RequestOption {
+	return func(o *RequestOpts) {
+		o.ResponseMetadata = md
+	}
+}
+
+// WithIdempotencyKey lets servers with WithServerIdempotency recognize retries of the same request. The retry
+// middleware sets a key for each call unless one is given.
+func WithIdempotencyKey(key string) RequestOption {
+	return func(o *RequestOpts) {
+		o.IdempotencyKey = key
+	}
+}
+
 func WithRequestInterceptors[T RequestInterceptor](interceptors ...T) RequestOption {
 	return func(o *RequestOpts) {
 		o.Interceptors = slices.Grow(o.Interceptors, len(interceptors))
@@ -0,0 +1,133 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package psrpc
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+var errNoClaims = NewErrorf(Unavailable, "no servers available")
+
+// WeightedRandomSelection picks a server at random, with probability proportional to its affinity
+func WeightedRandomSelection() SelectionFunc {
+	return weightedRandomSelection(rand.Float64)
+}
+
+func weightedRandomSelection(random func() float64) SelectionFunc {
+	return func(claims []*Claim) (string, error) {
+		if len(claims) == 0 {
+			return "", errNoClaims
+		}
+
+		var total float64
+		for _, c := range claims {
+			total += float64(c.Affinity)
+		}
+		if total <= 0 {
+			return claims[int(random()*float64(len(claims)))].ServerID, nil
+		}
+
+		r := random() * total
+		for _, c := range claims {
+			r -= float64(c.Affinity)
+			if r < 0 {
+				return c.ServerID, nil
+			}
+		}
+		return claims[len(claims)-1].ServerID, nil
+	}
+}
+
+// PowerOfTwoChoicesSelection compares two servers picked at random, and selects the one with the higher affinity
+func PowerOfTwoChoicesSelection() SelectionFunc {
+	return powerOfTwoChoicesSelection(rand.Intn)
+}
+
+func powerOfTwoChoicesSelection(intn func(int) int) SelectionFunc {
+	return func(claims []*Claim) (string, error) {
+		switch len(claims) {
+		case 0:
+			return "", errNoClaims
+		case 1:
+			return claims[0].ServerID, nil
+		}
+
+		a := intn(len(claims))
+		b := intn(len(claims) - 1)
+		if b >= a {
+			b++
+		}
+		if claims[b].Affinity > claims[a].Affinity {
+			return claims[b].ServerID, nil
+		}
+		return claims[a].ServerID, nil
+	}
+}
+
+// ConsistentHashSelection maps each request's routing key to a server using rendezvous hashing, so requests
+// with the same key go to the same server for as long as it keeps claiming them. Requests without a routing
+// key all hash to the same server.
+func ConsistentHashSelection() KeyedSelectionFunc {
+	return func(key string, claims []*Claim) (string, error) {
+		if len(claims) == 0 {
+			return "", errNoClaims
+		}
+
+		var serverID string
+		var max uint64
+		for _, c := range claims {
+			h := fnv.New64a()
+			_, _ = h.Write([]byte(key))
+			_, _ = h.Write([]byte(c.ServerID))
+			if v := h.Sum64(); serverID == "" || v > max {
+				serverID = c.ServerID
+				max = v
+			}
+		}
+		return serverID, nil
+	}
+}
+
+// RoundRobinSelection cycles through the claiming servers in ID order. The returned function keeps
+// track of the last selected server, so it should be reused across requests.
+func RoundRobinSelection() SelectionFunc {
+	var mu sync.Mutex
+	var last string
+
+	return func(claims []*Claim) (string, error) {
+		if len(claims) == 0 {
+			return "", errNoClaims
+		}
+
+		ids := make([]string, len(claims))
+		for i, c := range claims {
+			ids[i] = c.ServerID
+		}
+		sort.Strings(ids)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		i := sort.SearchStrings(ids, last)
+		if i < len(ids) && ids[i] == last {
+			i++
+		}
+		last = ids[i%len(ids)]
+		return last, nil
+	}
+}
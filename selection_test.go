@@ -0,0 +1,114 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package psrpc
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectionStrategies(t *testing.T) {
+	claims := []*Claim{
+		{ServerID: "c", Affinity: 0.6},
+		{ServerID: "a", Affinity: 0.3},
+		{ServerID: "b", Affinity: 0.1},
+	}
+
+	t.Run("WeightedRandom", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(1))
+		selectionFunc := weightedRandomSelection(rng.Float64)
+
+		counts := map[string]int{}
+		for i := 0; i < 1000; i++ {
+			id, err := selectionFunc(claims)
+			require.NoError(t, err)
+			counts[id]++
+		}
+		require.Greater(t, counts["c"], counts["a"])
+		require.Greater(t, counts["a"], counts["b"])
+		require.Greater(t, counts["b"], 0)
+	})
+
+	t.Run("PowerOfTwoChoices", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(1))
+		selectionFunc := powerOfTwoChoicesSelection(rng.Intn)
+
+		counts := map[string]int{}
+		for i := 0; i < 1000; i++ {
+			id, err := selectionFunc(claims)
+			require.NoError(t, err)
+			counts[id]++
+		}
+		require.Greater(t, counts["c"], counts["a"])
+		require.Zero(t, counts["b"])
+	})
+
+	t.Run("ConsistentHash", func(t *testing.T) {
+		selectionFunc := ConsistentHashSelection()
+		id, err := selectionFunc("key", claims)
+		require.NoError(t, err)
+		for i := 0; i < 10; i++ {
+			next, err := ConsistentHashSelection()("key", claims)
+			require.NoError(t, err)
+			require.Equal(t, id, next)
+		}
+
+		servers := map[string]bool{}
+		for i := 0; i < 20; i++ {
+			next, err := selectionFunc(fmt.Sprintf("key-%d", i), claims)
+			require.NoError(t, err)
+			servers[next] = true
+		}
+		require.Len(t, servers, len(claims))
+
+		var remaining []*Claim
+		for _, c := range claims {
+			if c.ServerID != id {
+				remaining = append(remaining, c)
+			}
+		}
+		next, err := selectionFunc("key", remaining)
+		require.NoError(t, err)
+		require.NotEqual(t, id, next)
+	})
+
+	t.Run("RoundRobin", func(t *testing.T) {
+		selectionFunc := RoundRobinSelection()
+		for _, expected := range []string{"a", "b", "c", "a"} {
+			id, err := selectionFunc(claims)
+			require.NoError(t, err)
+			require.Equal(t, expected, id)
+		}
+		id, err := selectionFunc(claims[:2])
+		require.NoError(t, err)
+		require.Equal(t, "c", id)
+	})
+
+	t.Run("NoClaims", func(t *testing.T) {
+		for _, selectionFunc := range []SelectionFunc{
+			WeightedRandomSelection(),
+			PowerOfTwoChoicesSelection(),
+			RoundRobinSelection(),
+		} {
+			_, err := selectionFunc(nil)
+			require.Error(t, err)
+		}
+		_, err := ConsistentHashSelection()("key", nil)
+		require.Error(t, err)
+	})
+}
@@ -21,7 +21,10 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
-const DefaultServerTimeout = time.Second * 3
+const (
+	DefaultServerTimeout      = time.Second * 3
+	DefaultLoadAffinityWeight = 0.5
+)
 
 type ServerOption func(*ServerOpts)
 
@@ -32,6 +35,21 @@ type ServerOpts struct {
 	Interceptors       []ServerRPCInterceptor
 	StreamInterceptors []StreamInterceptor
 	ChainedInterceptor ServerRPCInterceptor
+	MaxConcurrency     int
+	HandlerConcurrency int
+	QueueSize          int
+	QueueTimeout       time.Duration
+	LoadAffinity       LoadAffinityOpts
+	StreamWindow       int
+	StreamKeepalive    KeepaliveOpts
+	StreamResumeHook   StreamResumeHook
+	Idempotency        IdempotencyOpts
+	SubscriptionState  SubscriptionStateFunc
+}
+
+type LoadAffinityOpts struct {
+	Capacity int     // in-flight requests or open streams at which a handler stops claiming requests
+	Weight   float32 // (default 0.5) weight given to load when blended with a handler's affinity function
 }
 
 func WithServerID(id string) ServerOption {
@@ -54,6 +72,81 @@ func WithServerChannelSize(size int) ServerOption {
 	}
 }
 
+// WithServerSubscriptionStateFunc is called when the server's subscriptions fail and are restored
+func WithServerSubscriptionStateFunc(fn SubscriptionStateFunc) ServerOption {
+	return func(o *ServerOpts) {
+		o.SubscriptionState = fn
+	}
+}
+
+// WithServerMaxConcurrency limits the number of requests handled at once across all of the server's handlers
+func WithServerMaxConcurrency(limit int) ServerOption {
+	return func(o *ServerOpts) {
+		o.MaxConcurrency = limit
+	}
+}
+
+// WithHandlerConcurrency limits the number of requests handled at once by each registered handler
+func WithHandlerConcurrency(limit int) ServerOption {
+	return func(o *ServerOpts) {
+		o.HandlerConcurrency = limit
+	}
+}
+
+// WithServerQueue allows up to size requests to wait for a free handler once a concurrency limit is reached.
+// Requests which cannot be queued, or wait longer than timeout, are rejected with ResourceExhausted.
+func WithServerQueue(size int, timeout time.Duration) ServerOption {
+	return func(o *ServerOpts) {
+		o.QueueSize = size
+		o.QueueTimeout = timeout
+	}
+}
+
+// WithServerLoadAffinity computes handler affinity from the number of in-flight requests, or open streams,
+// against a capacity. Handlers registered with an affinity function blend the two scores.
+func WithServerLoadAffinity(opts LoadAffinityOpts) ServerOption {
+	return func(o *ServerOpts) {
+		o.LoadAffinity = opts
+	}
+}
+
+// WithServerStreamWindow enables credit based flow control on streams opened with this server. Clients may
+// send up to window messages ahead of the handler's consumer.
+func WithServerStreamWindow(window int) ServerOption {
+	return func(o *ServerOpts) {
+		o.StreamWindow = window
+	}
+}
+
+// WithServerStreamKeepalive pings clients while their streams are open, closing streams whose clients stop responding
+func WithServerStreamKeepalive(opts KeepaliveOpts) ServerOption {
+	return func(o *ServerOpts) {
+		o.StreamKeepalive = opts
+	}
+}
+
+// WithServerIdempotency deduplicates requests sent with an idempotency key. Duplicates of a request are
+// answered with its stored response, or wait for it while the first execution is in flight.
+func WithServerIdempotency(opts IdempotencyOpts) ServerOption {
+	return func(o *ServerOpts) {
+		if opts.TTL == 0 {
+			opts.TTL = DefaultIdempotencyTTL
+		}
+		o.Idempotency = opts
+	}
+}
+
+// StreamResumeHook is called before a handler runs for a resumed stream. It can restore application state for
+// the resume token, returning a context for the stream which carries it. An error rejects the stream.
+type StreamResumeHook func(ctx context.Context, info RPCInfo, token string) (context.Context, error)
+
+// WithServerStreamResumeHook sets the hook called when clients resume streams
+func WithServerStreamResumeHook(hook StreamResumeHook) ServerOption {
+	return func(o *ServerOpts) {
+		o.StreamResumeHook = hook
+	}
+}
+
 // Server interceptors wrap the service implementation
 type ServerRPCInterceptor func(ctx context.Context, req proto.Message, info RPCInfo, handler ServerRPCHandler) (proto.Message, error)
 type ServerRPCHandler func(context.Context, proto.Message) (proto.Message, error)
@@ -20,10 +20,20 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+const DefaultKeepaliveMisses = 3
+
+// KeepaliveOpts configures stream heartbeats. Each side with keepalive enabled pings its peer, and closes
+// the stream with ErrPeerUnresponsive when pings go unanswered.
+type KeepaliveOpts struct {
+	Interval time.Duration // time between pings
+	Misses   int           // (default 3) consecutive unanswered intervals before the peer is considered dead
+}
+
 type StreamOption func(*StreamOpts)
 
 type StreamOpts struct {
 	Timeout time.Duration
+	Seq     *uint64
 }
 
 func WithTimeout(timeout time.Duration) StreamOption {
@@ -32,6 +42,14 @@ func WithTimeout(timeout time.Duration) StreamOption {
 	}
 }
 
+// WithSeq shares a message's sequence number between attempts to send it. The stream sets seq when it first
+// numbers the message, and later attempts with the same seq reuse the number, so the peer drops duplicates.
+func WithSeq(seq *uint64) StreamOption {
+	return func(o *StreamOpts) {
+		o.Seq = seq
+	}
+}
+
 type StreamInterceptor func(info RPCInfo, next StreamHandler) StreamHandler
 type StreamHandler interface {
 	Recv(msg proto.Message) error
@@ -20,13 +20,15 @@ import (
 	"google.golang.org/protobuf/proto"
 
 	"github.com/livekit/psrpc/internal/bus"
+	"github.com/livekit/psrpc/pkg/metadata"
 )
 
 type Subscription[MessageType proto.Message] bus.Subscription[MessageType]
 
 type Response[ResponseType proto.Message] struct {
-	Result ResponseType
-	Err    error
+	Result   ResponseType
+	Err      error
+	Metadata metadata.ResponseMetadata
 }
 
 type Stream[SendType, RecvType proto.Message] interface {
@@ -37,11 +39,43 @@ type Stream[SendType, RecvType proto.Message] interface {
 	Err() error
 }
 
+// StreamControl is implemented by both ends of a bidirectional stream
+type StreamControl interface {
+	// CloseSend signals that no more messages will be sent. The peer's channel is closed once its buffered
+	// messages drain, and messages can still be received until the stream is closed.
+	CloseSend() error
+
+	// ResumeToken identifies a resumable stream, or is empty if the stream is not resumable
+	ResumeToken() string
+
+	// SkippedMessages returns the number of messages from the peer which never arrived, and were skipped so
+	// that the messages after them could be delivered
+	SkippedMessages() uint64
+}
+
 type ClientStream[SendType, RecvType proto.Message] interface {
 	Stream[SendType, RecvType]
+	StreamControl
 }
 
 type ServerStream[SendType, RecvType proto.Message] interface {
 	Stream[SendType, RecvType]
+	StreamControl
 	Hijack()
 }
+
+// ReceiveStream is the client side of a server streaming RPC
+type ReceiveStream[RecvType proto.Message] interface {
+	Context() context.Context
+	Channel() <-chan RecvType
+	Close(cause error) error
+	Err() error
+	SkippedMessages() uint64
+}
+
+// SendStream is the server side of a server streaming RPC. The stream is closed when the handler returns.
+type SendStream[SendType proto.Message] interface {
+	Context() context.Context
+	Send(msg SendType, opts ...StreamOption) error
+	Err() error
+}